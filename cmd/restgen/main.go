@@ -0,0 +1,148 @@
+// Command restgen generates code from @RestOperation annotations.
+//
+// Usage:
+//
+//	restgen routes [-o zz_routes.go] [files, directories or patterns...]
+//	restgen openapi [-o openapi.yaml] [-format json|yaml] [-title name] [-version v] [-bearer] [files, directories or patterns...]
+//	restgen manifest [-o routes.json] [files, directories or patterns...]
+//
+// It is meant to be run by go generate, e.g.
+//
+//	//go:generate go run github.com/wellscui/go-rest-annotation/cmd/restgen routes
+//
+// When no files are given the file containing the directive ($GOFILE) is used.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	rest "github.com/wellscui/go-rest-annotation/http"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	var err error
+	switch os.Args[1] {
+	case "routes":
+		err = runRoutes(os.Args[2:])
+	case "openapi":
+		err = runOpenAPI(os.Args[2:])
+	case "manifest":
+		err = runManifest(os.Args[2:])
+	default:
+		usage()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "restgen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: restgen routes [-o file] [files, directories or patterns...]")
+	fmt.Fprintln(os.Stderr, "       restgen openapi [-o file] [-format json|yaml] [-title name] [-version v] [-bearer] [files, directories or patterns...]")
+	fmt.Fprintln(os.Stderr, "       restgen manifest [-o file] [files, directories or patterns...]")
+	os.Exit(2)
+}
+
+func runRoutes(args []string) error {
+	flags := flag.NewFlagSet("routes", flag.ExitOnError)
+	output := flags.String("o", rest.GeneratedFileName, "output file")
+	flags.Parse(args)
+	routes, err := parseInputs(flags.Args())
+	if err != nil {
+		return err
+	}
+	pkg := os.Getenv("GOPACKAGE")
+	if pkg == "" && len(routes) > 0 {
+		pkg = routes[0].Package
+	}
+	var buf bytes.Buffer
+	if err := rest.GenerateRegistration(&buf, pkg, routes); err != nil {
+		return err
+	}
+	return os.WriteFile(*output, buf.Bytes(), 0644)
+}
+
+func runOpenAPI(args []string) error {
+	flags := flag.NewFlagSet("openapi", flag.ExitOnError)
+	output := flags.String("o", "", "output file (default stdout)")
+	format := flags.String("format", "", "json or yaml (default from -o extension, else yaml)")
+	title := flags.String("title", "API", "API title")
+	version := flags.String("version", "1.0.0", "API version")
+	bearer := flags.Bool("bearer", false, "declare a bearer token security scheme required by authenticated routes")
+	flags.Parse(args)
+	routes, err := parseInputs(flags.Args())
+	if err != nil {
+		return err
+	}
+	options := rest.OpenAPIOptions{Title: *title, Version: *version}
+	if *bearer {
+		options.SecuritySchemes = map[string]*rest.OpenAPISecurityScheme{"bearerAuth": {Type: "http", Scheme: "bearer"}}
+	}
+	doc, err := rest.GenerateOpenAPI(routes, options)
+	if err != nil {
+		return err
+	}
+	if *format == "" {
+		*format = "yaml"
+		if strings.HasSuffix(*output, ".json") {
+			*format = "json"
+		}
+	}
+	var data []byte
+	switch *format {
+	case "json":
+		data, err = doc.JSON()
+	case "yaml":
+		data, err = doc.YAML()
+	default:
+		return fmt.Errorf("unknown format %s", *format)
+	}
+	if err != nil {
+		return err
+	}
+	if *output == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(*output, data, 0644)
+}
+
+func runManifest(args []string) error {
+	flags := flag.NewFlagSet("manifest", flag.ExitOnError)
+	output := flags.String("o", "", "output file (default stdout)")
+	flags.Parse(args)
+	routes, err := parseInputs(flags.Args())
+	if err != nil {
+		return err
+	}
+	data, err := rest.NewManifest(routes).JSON()
+	if err != nil {
+		return err
+	}
+	if *output == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(*output, data, 0644)
+}
+
+// parseInputs parses the given files, directories or patterns, defaulting to $GOFILE under go generate.
+func parseInputs(inputs []string) ([]*rest.RouteMetadata, error) {
+	if len(inputs) == 0 {
+		if gofile := os.Getenv("GOFILE"); gofile != "" {
+			inputs = []string{gofile}
+		}
+	}
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("no input files")
+	}
+	return rest.ParsePackages(inputs...)
+}
@@ -14,10 +14,12 @@ func main() {
 	handler := &person.Handler{}
 	router := mux.NewRouter()
 	rest.RegisterMiddleware("PersonMiddleWare", person.PersonMiddleWare)
-	err := rest.RegisterRoutes(router, handler, "./person/handler.go")
+	// RegisterHandlerRoutes is generated by `go generate ./...`, so no source files are needed at runtime.
+	err := person.RegisterHandlerRoutes(router, handler)
 	if err != nil {
 		log.Fatalf("Failed to register routes: %v", err)
 	}
+	router.Handle("/debug/routes", rest.RoutesHandler()).Methods("GET")
 	log.Println("Server starting on :8080")
 	log.Println("Try: curl http://localhost:8080/person/123")
 	log.Fatal(http.ListenAndServe(":8080", router))
@@ -2,11 +2,8 @@ package person
 
 import (
 	"context"
-	"encoding/json"
 	"log"
 	"net/http"
-
-	"github.com/gorilla/mux"
 )
 
 func (s *Handler) getPerson(ctx context.Context, uid string) (*Person, error) {
@@ -16,23 +13,27 @@ func (s *Handler) getPerson(ctx context.Context, uid string) (*Person, error) {
 	}, nil
 }
 
+//go:generate go run github.com/wellscui/go-rest-annotation/cmd/restgen routes
+
 // Handler represents your application service
 type Handler struct{}
 
-// GetPersonHTTP is the HTTP handler wrapper for getPerson
+// GetPersonRequest is bound from the request path and headers
+type GetPersonRequest struct {
+	UID  string `path:"uid"`
+	Addr string `header:"Address"`
+}
+
+// GetPerson returns the person identified by uid
 // @RestOperation( method = "GET", path = "/person/{uid}", middlewares = ["PersonMiddleWare"], timeout = 30, disableAuth = true )
-func (s *Handler) GetPersonHTTP(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	uid := vars["uid"]
-	person, err := s.getPerson(r.Context(), uid)
+func (s *Handler) GetPerson(ctx context.Context, req *GetPersonRequest) (*Person, error) {
+	person, err := s.getPerson(ctx, req.UID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		return nil, err
 	}
 	log.Println("Getting Address in Header")
-	person.Addr = r.Header.Get("Address")
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(person)
+	person.Addr = req.Addr
+	return person, nil
 }
 
 // Person represents a data model
@@ -18,10 +18,48 @@ func TestRegisterRoutes(t *testing.T) {
 		rest.RegisterMiddleware("PersonMiddleWare", PersonMiddleWare)
 		err := rest.RegisterRoutes(router, handler, "./handler.go")
 		require.NoError(t, err)
-		require.NotNil(t, router.Get("person.Handler.GetPersonHTTP"))
+		require.NotNil(t, router.Get("person.Handler.GetPerson"))
 		req := httptest.NewRequest("GET", "/person/bill", nil)
 		res := httptest.NewRecorder()
 		router.ServeHTTP(res, req)
 		assert.Equal(t, http.StatusOK, res.Code)
 	})
-}
\ No newline at end of file
+
+	t.Run("registers generated routes and handles request", func(t *testing.T) {
+		router := mux.NewRouter()
+		handler := &Handler{}
+		rest.RegisterMiddleware("PersonMiddleWare", PersonMiddleWare)
+		err := RegisterHandlerRoutes(router, handler)
+		require.NoError(t, err)
+		require.NotNil(t, router.Get("person.Handler.GetPerson"))
+		req := httptest.NewRequest("GET", "/person/bill", nil)
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		assert.Equal(t, http.StatusOK, res.Code)
+	})
+}
+
+func TestRegisterRoutesTo(t *testing.T) {
+	t.Run("registers generated routes on a ServeMux", func(t *testing.T) {
+		serveMux := http.NewServeMux()
+		registry := rest.NewRegistry()
+		require.NoError(t, registry.RegisterMiddleware("PersonMiddleWare", PersonMiddleWare))
+		err := RegisterHandlerRoutesTo(registry, rest.ServeMuxTarget(serveMux), &Handler{})
+		require.NoError(t, err)
+		req := httptest.NewRequest("GET", "/person/bill", nil)
+		res := httptest.NewRecorder()
+		serveMux.ServeHTTP(res, req)
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Contains(t, res.Body.String(), "bill")
+	})
+}
+
+func TestRegisterRoutesFS(t *testing.T) {
+	router := mux.NewRouter()
+	rest.RegisterMiddleware("PersonMiddleWare", PersonMiddleWare)
+	require.NoError(t, rest.RegisterRoutesFS(router, &Handler{}, Sources, "*.go"))
+	req := httptest.NewRequest("GET", "/person/bill", nil)
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusOK, res.Code)
+}
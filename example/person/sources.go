@@ -0,0 +1,9 @@
+package person
+
+import "embed"
+
+// Sources embeds the annotated handler sources, so routes can be registered with
+// rest.RegisterRoutesFS wherever the binary runs.
+//
+//go:embed handler.go
+var Sources embed.FS
@@ -0,0 +1,26 @@
+// Code generated by restgen. DO NOT EDIT.
+
+package person
+
+import (
+	"github.com/gorilla/mux"
+
+	rest "github.com/wellscui/go-rest-annotation/http"
+)
+
+// RegisterHandlerRoutes registers the annotated routes of Handler on router using the default registry.
+func RegisterHandlerRoutes(router *mux.Router, h *Handler) error {
+	return RegisterHandlerRoutesWith(rest.DefaultRegistry(), router, h)
+}
+
+// RegisterHandlerRoutesWith registers the annotated routes of Handler on router using registry.
+func RegisterHandlerRoutesWith(registry *rest.Registry, router *mux.Router, h *Handler) error {
+	return RegisterHandlerRoutesTo(registry, rest.MuxTarget(router), h)
+}
+
+// RegisterHandlerRoutesTo registers the annotated routes of Handler on target using registry.
+func RegisterHandlerRoutesTo(registry *rest.Registry, target rest.RouteTarget, h *Handler) error {
+	return registry.RegisterBoundRoutesTo(target,
+		rest.BoundRoute{Route: &rest.RouteMetadata{Operation: &rest.RestOperation{Method: "GET", Path: "/person/{uid}", Middlewares: []string{"PersonMiddleWare"}, Timeout: 30, DisableAuth: true}, HandlerMethod: "GetPerson", HandlerType: "Handler", Package: "person", SourceFile: "handler.go", ImportPath: "github.com/wellscui/go-rest-annotation/example/person", Doc: "GetPerson returns the person identified by uid", Signature: "func(ctx context.Context, req *GetPersonRequest) (*Person, error)"}, Handler: rest.TypedHandler(h.GetPerson)},
+	)
+}
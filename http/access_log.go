@@ -0,0 +1,145 @@
+package http
+
+import (
+	"bufio"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+// RequestIDHeader is the header the access log reads the request ID from. When the request
+// has none, the response header set by a middleware is used.
+const RequestIDHeader = "X-Request-ID"
+
+// SetLogger sets the logger used to report registered routes and conflict warnings.
+// Pass nil to use slog.Default().
+func (reg *Registry) SetLogger(logger *slog.Logger) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.logger = logger
+}
+
+// SetAccessLogger sets the logger access records are written to. Pass nil to use slog.Default(),
+// as it is when routes are registered.
+func (reg *Registry) SetAccessLogger(logger *slog.Logger) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.accessLogger = logger
+}
+
+// EnableAccessLog turns access logging on or off for every route registered afterwards.
+// Routes can still opt in with accessLog = true, or out with disableAccessLog = true.
+func (reg *Registry) EnableAccessLog(enabled bool) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.accessLog = enabled
+}
+
+func (reg *Registry) registrationLogger() *slog.Logger {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	if reg.logger == nil {
+		return slog.Default()
+	}
+	return reg.logger
+}
+
+// WithAccessLog wraps handler so every request of route is logged once it completes, with
+// its route name, method, templated path, status, bytes written, latency and request ID.
+// Routes that disable it, or neither enable it nor inherit it from the registry, are returned as is.
+func (reg *Registry) WithAccessLog(handler http.HandlerFunc, route *RouteMetadata) http.HandlerFunc {
+	reg.mu.RLock()
+	enabled, logger := reg.accessLog, reg.accessLogger
+	reg.mu.RUnlock()
+	op := route.Operation
+	if op.DisableAccessLog || !op.AccessLog && !enabled {
+		return handler
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	name, path := route.RouteName(), op.Path
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		handler(rec, r)
+		requestID := r.Header.Get(RequestIDHeader)
+		if requestID == "" {
+			requestID = w.Header().Get(RequestIDHeader)
+		}
+		logger.LogAttrs(r.Context(), slog.LevelInfo, "request",
+			slog.String("route", name),
+			slog.String("method", r.Method),
+			slog.String("path", path),
+			slog.Int("status", rec.statusCode()),
+			slog.Int64("bytes", rec.bytes),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("request_id", requestID),
+		)
+	}
+}
+
+// SetLogger sets the registration logger of the default registry.
+func SetLogger(logger *slog.Logger) {
+	defaultRegistry.SetLogger(logger)
+}
+
+// SetAccessLogger sets the access logger of the default registry.
+func SetAccessLogger(logger *slog.Logger) {
+	defaultRegistry.SetAccessLogger(logger)
+}
+
+// EnableAccessLog turns access logging on or off for routes registered through the default registry.
+func EnableAccessLog(enabled bool) {
+	defaultRegistry.EnableAccessLog(enabled)
+}
+
+// statusRecorder records the status code and number of bytes written through it.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	if s.status == 0 {
+		s.status = status
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Write(p []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	n, err := s.ResponseWriter.Write(p)
+	s.bytes += int64(n)
+	return n, err
+}
+
+// Flush sends the response written so far, so streaming handlers keep working.
+func (s *statusRecorder) Flush() {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	http.NewResponseController(s.ResponseWriter).Flush()
+}
+
+// Hijack lets the handler take over the connection, e.g. for WebSockets.
+func (s *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(s.ResponseWriter).Hijack()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// statusCode returns the recorded status, 200 when the handler wrote nothing.
+func (s *statusRecorder) statusCode() int {
+	if s.status == 0 {
+		return http.StatusOK
+	}
+	return s.status
+}
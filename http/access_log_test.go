@@ -0,0 +1,110 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type loggedHandler struct{}
+
+// @RestOperation( method = "GET", path = "/orders/{id:int}", disableAuth = true )
+func (h *loggedHandler) GetOrder(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusCreated)
+	w.Write([]byte("order"))
+}
+
+// @RestOperation( method = "GET", path = "/health", disableAuth = true, disableAccessLog = true )
+func (h *loggedHandler) Health(w http.ResponseWriter, r *http.Request) {}
+
+// @RestOperation( method = "POST", path = "/audit", disableAuth = true, accessLog = true )
+func (h *loggedHandler) Audit(w http.ResponseWriter, r *http.Request) {}
+
+func TestAccessLog(t *testing.T) {
+	setup := func(t *testing.T, enabled bool) (http.Handler, *bytes.Buffer) {
+		var buf bytes.Buffer
+		registry := NewRegistry()
+		registry.SetAccessLogger(slog.New(slog.NewJSONHandler(&buf, nil)))
+		registry.EnableAccessLog(enabled)
+		router := mux.NewRouter()
+		require.NoError(t, registry.RegisterRoutes(router, &loggedHandler{}, "./access_log_test.go"))
+		return router, &buf
+	}
+	records := func(t *testing.T, buf *bytes.Buffer) []map[string]any {
+		var result []map[string]any
+		dec := json.NewDecoder(buf)
+		for dec.More() {
+			var record map[string]any
+			require.NoError(t, dec.Decode(&record))
+			result = append(result, record)
+		}
+		return result
+	}
+
+	t.Run("logs routes when enabled globally", func(t *testing.T) {
+		router, buf := setup(t, true)
+		req := httptest.NewRequest("GET", "/orders/7", nil)
+		req.Header.Set(RequestIDHeader, "req-1")
+		serveRequest(router, req)
+		serve(router, "/health")
+		logged := records(t, buf)
+		require.Len(t, logged, 1)
+		assert.Equal(t, "request", logged[0]["msg"])
+		assert.Equal(t, "http.loggedHandler.GetOrder", logged[0]["route"])
+		assert.Equal(t, "GET", logged[0]["method"])
+		assert.Equal(t, "/orders/{id:int}", logged[0]["path"])
+		assert.Equal(t, float64(http.StatusCreated), logged[0]["status"])
+		assert.Equal(t, float64(len("order")), logged[0]["bytes"])
+		assert.Equal(t, "req-1", logged[0]["request_id"])
+		assert.Contains(t, logged[0], "latency")
+	})
+
+	t.Run("logs opted in routes only when disabled globally", func(t *testing.T) {
+		router, buf := setup(t, false)
+		serve(router, "/orders/7")
+		serveRequest(router, httptest.NewRequest("POST", "/audit", nil))
+		logged := records(t, buf)
+		require.Len(t, logged, 1)
+		assert.Equal(t, "http.loggedHandler.Audit", logged[0]["route"])
+		assert.Equal(t, float64(http.StatusOK), logged[0]["status"])
+	})
+
+	t.Run("logs requests rejected before the handler", func(t *testing.T) {
+		router, buf := setup(t, true)
+		serve(router, "/orders/99999999999999999999999")
+		logged := records(t, buf)
+		require.Len(t, logged, 1)
+		assert.Equal(t, float64(http.StatusBadRequest), logged[0]["status"])
+	})
+
+	t.Run("logs concurrent requests to the default logger", func(t *testing.T) {
+		var buf bytes.Buffer
+		defaultLogger := slog.Default()
+		slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+		defer slog.SetDefault(defaultLogger)
+		registry := NewRegistry()
+		registry.EnableAccessLog(true)
+		router := mux.NewRouter()
+		require.NoError(t, registry.RegisterRoutes(router, &loggedHandler{}, "./access_log_test.go"))
+		buf.Reset()
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				serve(router, "/orders/7")
+			}()
+		}
+		wg.Wait()
+		assert.Len(t, records(t, &buf), 10)
+	})
+}
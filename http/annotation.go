@@ -0,0 +1,463 @@
+package http
+
+import (
+	"fmt"
+	"go/token"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// AnnotationError reports a malformed annotation and where it occurred. Pos carries
+// file:line:col when the annotation was read from a source file; otherwise it is
+// relative to the annotation text.
+type AnnotationError struct {
+	Pos    token.Position
+	Offset int
+	Msg    string
+	Err    error
+}
+
+func (e *AnnotationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+}
+
+func (e *AnnotationError) Unwrap() error {
+	return e.Err
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokDuration
+	tokAt
+	tokLParen
+	tokRParen
+	tokLBrack
+	tokRBrack
+	tokLBrace
+	tokRBrace
+	tokAssign
+	tokComma
+)
+
+var tokenNames = map[tokenKind]string{
+	tokEOF:      "end of annotation",
+	tokIdent:    "identifier",
+	tokString:   "string",
+	tokNumber:   "number",
+	tokDuration: "duration",
+	tokAt:       "'@'",
+	tokLParen:   "'('",
+	tokRParen:   "')'",
+	tokLBrack:   "'['",
+	tokRBrack:   "']'",
+	tokLBrace:   "'{'",
+	tokRBrace:   "'}'",
+	tokAssign:   "'='",
+	tokComma:    "','",
+}
+
+func (k tokenKind) String() string {
+	return tokenNames[k]
+}
+
+var punctuation = map[byte]tokenKind{'@': tokAt, '(': tokLParen, ')': tokRParen, '[': tokLBrack, ']': tokRBrack, '{': tokLBrace, '}': tokRBrace, '=': tokAssign, ',': tokComma}
+
+type annotationToken struct {
+	kind   tokenKind
+	text   string
+	offset int
+}
+
+// annotationLexer splits annotation text into tokens. Offsets are byte offsets into src.
+type annotationLexer struct {
+	src string
+	pos int
+	// refIdents lets identifiers contain dashes and dots, so that middleware references
+	// such as "request-id" or "auth.Bearer" are single tokens.
+	refIdents bool
+}
+
+func (l *annotationLexer) next() (annotationToken, error) {
+	for l.pos < len(l.src) {
+		r, size := utf8.DecodeRuneInString(l.src[l.pos:])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		l.pos += size
+	}
+	start := l.pos
+	if start >= len(l.src) {
+		return annotationToken{kind: tokEOF, offset: start}, nil
+	}
+	c := l.src[start]
+	if kind, ok := punctuation[c]; ok {
+		l.pos++
+		return annotationToken{kind: kind, text: string(c), offset: start}, nil
+	}
+	switch {
+	case c == '"' || c == '`':
+		return l.scanString(c)
+	case c == '-' || c == '+' || c == '.' || isDigit(c):
+		return l.scanNumber()
+	case c == '_' || c < utf8.RuneSelf && unicode.IsLetter(rune(c)):
+		for l.pos < len(l.src) && (isIdentChar(l.src[l.pos]) || l.refIdents && (l.src[l.pos] == '-' || l.src[l.pos] == '.')) {
+			l.pos++
+		}
+		return annotationToken{kind: tokIdent, text: l.src[start:l.pos], offset: start}, nil
+	}
+	r, _ := utf8.DecodeRuneInString(l.src[start:])
+	return annotationToken{}, l.errorf(start, "unexpected character %q", r)
+}
+
+func (l *annotationLexer) scanString(quote byte) (annotationToken, error) {
+	start := l.pos
+	l.pos++
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == quote:
+			l.pos++
+			value, err := strconv.Unquote(l.src[start:l.pos])
+			if err != nil {
+				return annotationToken{}, l.errorf(start, "invalid string %s", l.src[start:l.pos])
+			}
+			return annotationToken{kind: tokString, text: value, offset: start}, nil
+		case c == '\\' && quote == '"':
+			l.pos += 2
+		case c == '\n' && quote == '"':
+			return annotationToken{}, l.errorf(start, "unterminated string")
+		default:
+			l.pos++
+		}
+	}
+	return annotationToken{}, l.errorf(start, "unterminated string")
+}
+
+// scanNumber scans an integer, a float, or a duration such as 1m30s.
+func (l *annotationLexer) scanNumber() (annotationToken, error) {
+	start := l.pos
+	if c := l.src[l.pos]; c == '-' || c == '+' {
+		l.pos++
+	}
+	for l.pos < len(l.src) && (isDigit(l.src[l.pos]) || l.src[l.pos] == '.') {
+		l.pos++
+	}
+	if l.pos < len(l.src) && (l.src[l.pos] == 'e' || l.src[l.pos] == 'E') && l.pos+1 < len(l.src) && (isDigit(l.src[l.pos+1]) || l.src[l.pos+1] == '-' || l.src[l.pos+1] == '+') {
+		l.pos += 2
+		for l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+			l.pos++
+		}
+	}
+	kind := tokNumber
+	for l.pos < len(l.src) {
+		r, size := utf8.DecodeRuneInString(l.src[l.pos:])
+		if !unicode.IsLetter(r) && !isDigit(l.src[l.pos]) && l.src[l.pos] != '.' {
+			break
+		}
+		l.pos += size
+		kind = tokDuration
+	}
+	text := l.src[start:l.pos]
+	if kind == tokDuration {
+		if _, err := time.ParseDuration(text); err != nil {
+			return annotationToken{}, l.errorf(start, "invalid duration %s", text)
+		}
+	} else if _, err := strconv.ParseFloat(text, 64); err != nil {
+		return annotationToken{}, l.errorf(start, "invalid number %s", text)
+	}
+	return annotationToken{kind: kind, text: text, offset: start}, nil
+}
+
+func (l *annotationLexer) errorf(offset int, format string, args ...any) error {
+	return newAnnotationError(l.src, offset, fmt.Sprintf(format, args...), ErrInvalidFormat)
+}
+
+func newAnnotationError(src string, offset int, msg string, err error) *AnnotationError {
+	line := 1 + strings.Count(src[:offset], "\n")
+	column := offset - strings.LastIndex(src[:offset], "\n")
+	return &AnnotationError{Pos: token.Position{Line: line, Column: column}, Offset: offset, Msg: msg, Err: err}
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || isDigit(c) || c < utf8.RuneSelf && unicode.IsLetter(rune(c))
+}
+
+type valueKind int
+
+const (
+	stringValue valueKind = iota
+	numberValue
+	durationValue
+	boolValue
+	identValue
+	arrayValue
+	objectValue
+)
+
+var valueKindNames = map[valueKind]string{
+	stringValue:   "string",
+	numberValue:   "number",
+	durationValue: "duration",
+	boolValue:     "boolean",
+	identValue:    "identifier",
+	arrayValue:    "array",
+	objectValue:   "object",
+}
+
+func (k valueKind) String() string {
+	return valueKindNames[k]
+}
+
+// annotationValue is a parsed value: strings are unquoted, numbers and durations keep their source text.
+// Arrays hold their elements and objects such as { key = value } their fields.
+type annotationValue struct {
+	kind   valueKind
+	text   string
+	offset int
+	elems  []*annotationValue
+	fields []*annotationArg
+}
+
+type annotationArg struct {
+	key    string
+	offset int
+	value  *annotationValue
+}
+
+// annotation is the parsed form of @Name(key = value, ...).
+type annotation struct {
+	name   string
+	offset int
+	args   []*annotationArg
+	src    string
+}
+
+func (a *annotation) has(key string) bool {
+	for _, arg := range a.args {
+		if arg.key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// errorAt reports msg at offset within the annotation text.
+func (a *annotation) errorAt(offset int, err error, format string, args ...any) *AnnotationError {
+	return newAnnotationError(a.src, offset, fmt.Sprintf(format, args...), err)
+}
+
+// parseAnnotation parses the @name(...) annotation found in text. Text before the
+// annotation and after its closing parenthesis is ignored. ErrInvalidFormat is returned
+// as is when the annotation or its argument list is missing.
+func parseAnnotation(text, name string) (*annotation, error) {
+	start := strings.Index(text, "@"+name)
+	if start < 0 {
+		return nil, ErrInvalidFormat
+	}
+	p := &annotationParser{lex: &annotationLexer{src: text, pos: start + 1}}
+	if err := p.advance(); err != nil || p.tok.kind != tokIdent || p.tok.text != name {
+		return nil, ErrInvalidFormat
+	}
+	if err := p.advance(); err != nil || p.tok.kind != tokLParen {
+		return nil, ErrInvalidFormat
+	}
+	ann := &annotation{name: name, offset: start, src: text}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	args, err := p.parseArgs(tokRParen)
+	if err != nil {
+		return nil, err
+	}
+	ann.args = args
+	return ann, nil
+}
+
+type annotationParser struct {
+	lex *annotationLexer
+	tok annotationToken
+}
+
+func (p *annotationParser) advance() error {
+	tok, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+// parseArgs parses key = value pairs up to the closing token, which is left as the current token.
+func (p *annotationParser) parseArgs(closing tokenKind) ([]*annotationArg, error) {
+	var args []*annotationArg
+	seen := make(map[string]bool)
+	for p.tok.kind != closing {
+		if p.tok.kind != tokIdent {
+			return nil, p.unexpected("parameter name")
+		}
+		arg := &annotationArg{key: p.tok.text, offset: p.tok.offset}
+		if seen[arg.key] {
+			return nil, p.errorf(arg.offset, "duplicate parameter %s", arg.key)
+		}
+		seen[arg.key] = true
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokAssign {
+			return nil, p.unexpected(fmt.Sprintf("'=' after %s", arg.key))
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		arg.value = value
+		args = append(args, arg)
+		if err := p.skipComma(closing); err != nil {
+			return nil, err
+		}
+	}
+	return args, nil
+}
+
+// skipComma consumes an optional ',' separator. It fails at the end of the text so
+// that a missing closing token is reported.
+func (p *annotationParser) skipComma(closing tokenKind) error {
+	if p.tok.kind == tokComma {
+		if err := p.advance(); err != nil {
+			return err
+		}
+	}
+	if p.tok.kind == tokEOF {
+		return p.unexpected(closing.String())
+	}
+	return nil
+}
+
+func (p *annotationParser) parseValue() (*annotationValue, error) {
+	tok := p.tok
+	value := &annotationValue{text: tok.text, offset: tok.offset}
+	switch tok.kind {
+	case tokString:
+		value.kind = stringValue
+	case tokNumber:
+		value.kind = numberValue
+	case tokDuration:
+		value.kind = durationValue
+	case tokIdent:
+		value.kind = identValue
+		if tok.text == "true" || tok.text == "false" {
+			value.kind = boolValue
+		}
+	case tokLBrack:
+		value.kind = arrayValue
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		for p.tok.kind != tokRBrack {
+			elem, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			value.elems = append(value.elems, elem)
+			if err := p.skipComma(tokRBrack); err != nil {
+				return nil, err
+			}
+		}
+	case tokLBrace:
+		value.kind = objectValue
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		fields, err := p.parseArgs(tokRBrace)
+		if err != nil {
+			return nil, err
+		}
+		value.fields = fields
+	default:
+		return nil, p.unexpected("value")
+	}
+	return value, p.advance()
+}
+
+func (p *annotationParser) unexpected(expected string) error {
+	found := p.tok.kind.String()
+	if p.tok.kind != tokEOF {
+		found = fmt.Sprintf("%s %q", found, p.tok.text)
+	}
+	return p.errorf(p.tok.offset, "expected %s, found %s", expected, found)
+}
+
+func (p *annotationParser) errorf(offset int, format string, args ...any) error {
+	return p.lex.errorf(offset, format, args...)
+}
+
+func (v *annotationValue) asString() (string, error) {
+	if v.kind != stringValue {
+		return "", fmt.Errorf("expected string, found %s", v.kind)
+	}
+	return v.text, nil
+}
+
+func (v *annotationValue) asBool() (bool, error) {
+	if v.kind != boolValue {
+		return false, fmt.Errorf("expected boolean, found %s", v.kind)
+	}
+	return v.text == "true", nil
+}
+
+func (v *annotationValue) asInt() (int, error) {
+	if v.kind != numberValue {
+		return 0, fmt.Errorf("expected integer, found %s %s", v.kind, v.text)
+	}
+	return strconv.Atoi(v.text)
+}
+
+func (v *annotationValue) asFloat() (float64, error) {
+	if v.kind != numberValue {
+		return 0, fmt.Errorf("expected number, found %s %s", v.kind, v.text)
+	}
+	return strconv.ParseFloat(v.text, 64)
+}
+
+func (v *annotationValue) asDuration() (time.Duration, error) {
+	switch v.kind {
+	case durationValue:
+		return time.ParseDuration(v.text)
+	case stringValue:
+		return time.ParseDuration(v.text)
+	}
+	return 0, fmt.Errorf("expected duration, found %s %s", v.kind, v.text)
+}
+
+func (v *annotationValue) asStrings() ([]string, error) {
+	if v.kind != arrayValue {
+		return nil, fmt.Errorf("expected array, found %s", v.kind)
+	}
+	if len(v.elems) == 0 {
+		return nil, nil
+	}
+	result := make([]string, 0, len(v.elems))
+	for _, elem := range v.elems {
+		s, err := elem.asString()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, s)
+	}
+	return result, nil
+}
@@ -0,0 +1,138 @@
+package http
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAnnotationGrammar(t *testing.T) {
+	t.Run("parses strings containing commas, spaces and escapes", func(t *testing.T) {
+		op, err := ParseRestOperation(`@RestOperation( method = "GET", path = "/search/{a},{b} \"x\"" )`)
+		require.NoError(t, err)
+		assert.Equal(t, `/search/{a},{b} "x"`, op.Path)
+	})
+
+	t.Run("parses raw strings", func(t *testing.T) {
+		op, err := ParseRestOperation("@RestOperation( method = `GET`, path = `/a\\b` )")
+		require.NoError(t, err)
+		assert.Equal(t, `/a\b`, op.Path)
+	})
+
+	t.Run("parses arrays separated by commas or spaces", func(t *testing.T) {
+		op, err := ParseRestOperation(`@RestOperation( method = "GET", path = "/", middlewares = ["a", "b" "c",] )`)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a", "b", "c"}, op.Middlewares)
+	})
+
+	t.Run("parses parameters separated by spaces", func(t *testing.T) {
+		op, err := ParseRestOperation(`@RestOperation( method = "GET" path = "/" disableAuth = true )`)
+		require.NoError(t, err)
+		assert.True(t, op.DisableAuth)
+	})
+
+	t.Run("parses duration timeout", func(t *testing.T) {
+		op, err := ParseRestOperation(`@RestOperation( method = "GET", path = "/", timeout = 1m30s )`)
+		require.NoError(t, err)
+		assert.Equal(t, 90, op.Timeout)
+	})
+
+	t.Run("rejects fractional second timeout", func(t *testing.T) {
+		_, err := ParseRestOperation(`@RestOperation( method = "GET", path = "/", timeout = 1500ms )`)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid timeout value")
+	})
+
+	t.Run("ignores text around the annotation", func(t *testing.T) {
+		op, err := ParseRestOperation(`/* @RestOperation( method = "GET", path = "/" ) */`)
+		require.NoError(t, err)
+		assert.Equal(t, "/", op.Path)
+	})
+
+	t.Run("reports unknown parameter with position", func(t *testing.T) {
+		_, err := ParseRestOperation(`@RestOperation( method = "GET", path = "/", timout = 3 )`)
+		var annErr *AnnotationError
+		require.True(t, errors.As(err, &annErr))
+		assert.ErrorIs(t, err, ErrUnknownKey)
+		assert.Equal(t, 1, annErr.Pos.Line)
+		assert.Equal(t, 45, annErr.Pos.Column)
+		assert.Equal(t, "1:45: unknown parameter timout", err.Error())
+	})
+
+	t.Run("reports type mismatch", func(t *testing.T) {
+		_, err := ParseRestOperation(`@RestOperation( method = GET, path = "/" )`)
+		assert.Error(t, err)
+		assert.Equal(t, "1:26: invalid method value: expected string, found identifier", err.Error())
+	})
+
+	t.Run("reports duplicate parameter", func(t *testing.T) {
+		_, err := ParseRestOperation(`@RestOperation( method = "GET", method = "POST", path = "/" )`)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "duplicate parameter method")
+	})
+
+	t.Run("reports unterminated string", func(t *testing.T) {
+		_, err := ParseRestOperation(`@RestOperation( method = "GET, path = "/" )`)
+		assert.ErrorIs(t, err, ErrInvalidFormat)
+	})
+
+	t.Run("reports missing closing parenthesis", func(t *testing.T) {
+		_, err := ParseRestOperation(`@RestOperation( method = "GET", path = "/"`)
+		assert.ErrorIs(t, err, ErrInvalidFormat)
+		assert.Contains(t, err.Error(), "expected ')', found end of annotation")
+	})
+
+	t.Run("reports missing array terminator", func(t *testing.T) {
+		_, err := ParseRestOperation(`@RestOperation( method = "GET", path = "/", middlewares = ["a" )`)
+		assert.ErrorIs(t, err, ErrInvalidFormat)
+	})
+
+	t.Run("rejects dashes and dots in parameter names", func(t *testing.T) {
+		_, err := ParseRestOperation(`@RestOperation( method = "GET", path = "/", disable-auth = true )`)
+		assert.ErrorIs(t, err, ErrInvalidFormat)
+		op, err := ParseRestOperation(`@RestOperation( method = "GET", path = "/", middlewares = ["request-id", "auth.Bearer(realm=api)"] )`)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"request-id", "auth.Bearer(realm=api)"}, op.Middlewares)
+	})
+
+	t.Run("parses objects", func(t *testing.T) {
+		ann, err := parseAnnotation(`@RestOperation( cors = { origins = ["https://a.example"] credentials = true } )`, "RestOperation")
+		require.NoError(t, err)
+		value := ann.args[0].value
+		assert.Equal(t, objectValue, value.kind)
+		require.Len(t, value.fields, 2)
+		assert.Equal(t, "credentials", value.fields[1].key)
+	})
+
+	t.Run("reports duplicate object field", func(t *testing.T) {
+		_, err := ParseRestOperation(`@RestOperation( method = "GET", path = "/", cors = { origins = ["*"], origins = ["*"] } )`)
+		assert.ErrorContains(t, err, "duplicate parameter origins")
+	})
+
+	t.Run("reports missing object terminator", func(t *testing.T) {
+		_, err := ParseRestOperation(`@RestOperation( method = "GET", path = "/", cors = { origins = ["*"] )`)
+		assert.ErrorContains(t, err, "expected parameter name, found ')'")
+	})
+}
+
+func TestParseRouteMetadataErrorPosition(t *testing.T) {
+	content := `package main
+
+// @RestOperation( method = "GET", path = "/test", unknown = 1 )
+func (s *Service) test() {}
+
+type Service struct{}
+`
+	tmpFile := createTempFile(t, content)
+	defer os.Remove(tmpFile)
+	_, err := ParseRouteMetadata(tmpFile)
+	var annErr *AnnotationError
+	require.True(t, errors.As(err, &annErr))
+	assert.Equal(t, tmpFile, annErr.Pos.Filename)
+	assert.Equal(t, 3, annErr.Pos.Line)
+	assert.Equal(t, 52, annErr.Pos.Column)
+	assert.Contains(t, err.Error(), tmpFile+":3:52: unknown parameter unknown")
+}
@@ -0,0 +1,135 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Principal identifies the caller of an authenticated request.
+type Principal interface {
+	Name() string
+}
+
+// Authenticator verifies the credentials carried by a request.
+type Authenticator interface {
+	// Authenticate returns the principal of the request, or an error when the credentials are missing or invalid.
+	Authenticate(r *http.Request) (Principal, error)
+	// Challenge returns the WWW-Authenticate header value sent with 401 responses, e.g. `Bearer realm="api"`.
+	Challenge() string
+}
+
+type principalKey struct{}
+
+var ErrNoAuthenticator = errors.New("no authenticator registered")
+
+// RegisterAuthenticator registers the authenticator applied to every route without disableAuth = true.
+func (reg *Registry) RegisterAuthenticator(a Authenticator) error {
+	if a == nil {
+		return errors.New("authenticator must not be nil")
+	}
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	if reg.authenticator != nil {
+		return errors.New("authenticator already registered")
+	}
+	reg.authenticator = a
+	return nil
+}
+
+// GetAuthenticator returns the registered authenticator.
+func (reg *Registry) GetAuthenticator() (Authenticator, error) {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	if reg.authenticator == nil {
+		return nil, ErrNoAuthenticator
+	}
+	return reg.authenticator, nil
+}
+
+// RequireAuthenticator sets whether registering a route without disableAuth = true fails with
+// ErrNoAuthenticator while no authenticator is registered, which is the default. Passing false
+// serves such routes unauthenticated, with a warning logged for each of them.
+func (reg *Registry) RequireAuthenticator(required bool) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.requireAuthenticator = required
+}
+
+// ClearAuthenticator removes the registered authenticator (useful for testing).
+func (reg *Registry) ClearAuthenticator() {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.authenticator = nil
+}
+
+// ApplyAuthentication wraps handler with the registered authenticator unless the operation disables auth.
+// Requests that fail authentication get a 401 with the authenticator's WWW-Authenticate challenge.
+// Without an authenticator it fails with ErrNoAuthenticator, unless RequireAuthenticator(false)
+// was called, in which case handler is returned as is.
+func (reg *Registry) ApplyAuthentication(handler http.HandlerFunc, restOperation RestOperation) (http.HandlerFunc, error) {
+	handler, _, err := reg.applyAuthentication(handler, restOperation)
+	return handler, err
+}
+
+// applyAuthentication is ApplyAuthentication, also reporting whether handler was wrapped.
+func (reg *Registry) applyAuthentication(handler http.HandlerFunc, restOperation RestOperation) (http.HandlerFunc, bool, error) {
+	if restOperation.DisableAuth {
+		return handler, false, nil
+	}
+	reg.mu.RLock()
+	auth, required := reg.authenticator, reg.requireAuthenticator
+	reg.mu.RUnlock()
+	if auth == nil {
+		if required {
+			return nil, false, fmt.Errorf("route requires authentication: %w", ErrNoAuthenticator)
+		}
+		return handler, false, nil
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, err := auth.Authenticate(r)
+		if err != nil || principal == nil {
+			w.Header().Set("WWW-Authenticate", auth.Challenge())
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		handler(w, r.WithContext(ContextWithPrincipal(r.Context(), principal)))
+	}, true, nil
+}
+
+// RegisterAuthenticator registers the authenticator of the default registry.
+func RegisterAuthenticator(a Authenticator) error {
+	return defaultRegistry.RegisterAuthenticator(a)
+}
+
+// GetAuthenticator returns the authenticator of the default registry.
+func GetAuthenticator() (Authenticator, error) {
+	return defaultRegistry.GetAuthenticator()
+}
+
+// RequireAuthenticator sets whether routes of the default registry require an authenticator.
+func RequireAuthenticator(required bool) {
+	defaultRegistry.RequireAuthenticator(required)
+}
+
+// ClearAuthenticator removes the authenticator of the default registry (useful for testing).
+func ClearAuthenticator() {
+	defaultRegistry.ClearAuthenticator()
+}
+
+// ApplyAuthentication wraps handler with the authenticator of the default registry.
+func ApplyAuthentication(handler http.HandlerFunc, restOperation RestOperation) (http.HandlerFunc, error) {
+	return defaultRegistry.ApplyAuthentication(handler, restOperation)
+}
+
+// PrincipalFromContext returns the principal stored by the authentication layer.
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+// ContextWithPrincipal returns a copy of ctx carrying the principal.
+func ContextWithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
@@ -0,0 +1,150 @@
+package http
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testPrincipal string
+
+func (p testPrincipal) Name() string { return string(p) }
+
+type tokenAuthenticator struct{}
+
+func (tokenAuthenticator) Authenticate(r *http.Request) (Principal, error) {
+	if r.Header.Get("Authorization") != "Bearer secret" {
+		return nil, errors.New("invalid token")
+	}
+	return testPrincipal("alice"), nil
+}
+
+func (tokenAuthenticator) Challenge() string { return `Bearer realm="test"` }
+
+type securedHandler struct{}
+
+// @RestOperation( method = "GET", path = "/secure" )
+func (h *securedHandler) Secure(w http.ResponseWriter, r *http.Request) {
+	principal, _ := PrincipalFromContext(r.Context())
+	w.Write([]byte(principal.Name()))
+}
+
+// @RestOperation( method = "GET", path = "/public", disableAuth = true )
+func (h *securedHandler) Public(w http.ResponseWriter, r *http.Request) {
+	if _, ok := PrincipalFromContext(r.Context()); ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func TestRegisterAuthenticator(t *testing.T) {
+	t.Run("registers authenticator successfully", func(t *testing.T) {
+		ClearAuthenticator()
+		defer ClearAuthenticator()
+		require.NoError(t, RegisterAuthenticator(tokenAuthenticator{}))
+		auth, err := GetAuthenticator()
+		assert.NoError(t, err)
+		assert.NotNil(t, auth)
+	})
+
+	t.Run("returns error for duplicate registration", func(t *testing.T) {
+		ClearAuthenticator()
+		defer ClearAuthenticator()
+		RegisterAuthenticator(tokenAuthenticator{})
+		err := RegisterAuthenticator(tokenAuthenticator{})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "already registered")
+	})
+
+	t.Run("returns error for nil authenticator", func(t *testing.T) {
+		err := RegisterAuthenticator(nil)
+		assert.Error(t, err)
+	})
+}
+
+func TestApplyAuthentication(t *testing.T) {
+	t.Run("returns error when no authenticator is registered", func(t *testing.T) {
+		handler := func(w http.ResponseWriter, r *http.Request) {}
+		wrapped, err := NewRegistry().ApplyAuthentication(handler, RestOperation{})
+		assert.ErrorIs(t, err, ErrNoAuthenticator)
+		assert.Nil(t, wrapped)
+	})
+
+	t.Run("serves routes unauthenticated when no authenticator is required", func(t *testing.T) {
+		registry := NewRegistry()
+		registry.RequireAuthenticator(false)
+		handler := func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+		}
+		wrapped, err := registry.ApplyAuthentication(handler, RestOperation{})
+		require.NoError(t, err)
+		res := httptest.NewRecorder()
+		wrapped(res, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, http.StatusAccepted, res.Code)
+	})
+
+	t.Run("warns about every route registered without authentication", func(t *testing.T) {
+		var logs bytes.Buffer
+		registry := NewRegistry()
+		registry.RequireAuthenticator(false)
+		registry.SetLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+		require.NoError(t, registry.RegisterRoutes(mux.NewRouter(), &securedHandler{}, "./auth_test.go"))
+		assert.Contains(t, logs.String(), "no authenticator registered, http.securedHandler.Secure is served without authentication")
+		assert.NotContains(t, logs.String(), "securedHandler.Public is served")
+	})
+
+	t.Run("fails registration when the authenticator is registered after the routes", func(t *testing.T) {
+		registry := NewRegistry()
+		err := registry.RegisterRoutes(mux.NewRouter(), &securedHandler{}, "./auth_test.go")
+		assert.ErrorIs(t, err, ErrNoAuthenticator)
+		assert.Empty(t, registry.Routes())
+	})
+
+	t.Run("skips authentication when disabled", func(t *testing.T) {
+		ClearAuthenticator()
+		handler := func(w http.ResponseWriter, r *http.Request) {}
+		wrapped, err := ApplyAuthentication(handler, RestOperation{DisableAuth: true})
+		assert.NoError(t, err)
+		assert.NotNil(t, wrapped)
+	})
+}
+
+func TestRegisterRoutesWithAuthentication(t *testing.T) {
+	ClearAuthenticator()
+	defer ClearAuthenticator()
+	require.NoError(t, RegisterAuthenticator(tokenAuthenticator{}))
+	router := mux.NewRouter()
+	require.NoError(t, RegisterRoutes(router, &securedHandler{}, "./auth_test.go"))
+
+	t.Run("rejects unauthenticated request with challenge", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/secure", nil)
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		assert.Equal(t, http.StatusUnauthorized, res.Code)
+		assert.Equal(t, `Bearer realm="test"`, res.Header().Get("WWW-Authenticate"))
+	})
+
+	t.Run("exposes principal to authenticated request", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/secure", nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, "alice", res.Body.String())
+	})
+
+	t.Run("does not authenticate routes with disableAuth", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/public", nil)
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		assert.Equal(t, http.StatusOK, res.Code)
+	})
+}
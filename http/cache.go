@@ -0,0 +1,273 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// DefaultMaxCachedResponseSize is the size, in bytes, up to which the responses of GET operations
+// declaring cache or etag are buffered, see SetMaxCachedResponseSize.
+const DefaultMaxCachedResponseSize = 1 << 20
+
+// SetMaxCachedResponseSize sets the size, in bytes, up to which the responses of routes registered
+// afterwards with cache or etag are buffered. Pass 0 to use DefaultMaxCachedResponseSize.
+func (reg *Registry) SetMaxCachedResponseSize(size int) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.maxCachedResponseSize = size
+}
+
+// SetMaxCachedResponseSize sets the buffered response size of the default registry.
+func SetMaxCachedResponseSize(size int) {
+	defaultRegistry.SetMaxCachedResponseSize(size)
+}
+
+// withCaching buffers the responses of GET operations declaring cache or etag. Successful
+// responses get the Cache-Control header and an ETag hashed from their body, unless the handler
+// set them, and are answered with 304 Not Modified when the request's If-None-Match, or else
+// If-Modified-Since against the handler's Last-Modified header, shows the client is up to date.
+// Buffering holds back streamed responses: those larger than the maximum size, or flushed by
+// the handler, are passed through from then on, without a generated ETag or 304 answer.
+func (reg *Registry) withCaching(handler http.HandlerFunc, op *RestOperation) http.HandlerFunc {
+	if op.Method != http.MethodGet || op.Cache == "" && !op.ETag {
+		return handler
+	}
+	reg.mu.RLock()
+	maxSize := reg.maxCachedResponseSize
+	reg.mu.RUnlock()
+	if maxSize <= 0 {
+		maxSize = DefaultMaxCachedResponseSize
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		buf := &bufferedResponse{w: w, header: make(http.Header), maxSize: maxSize, cache: op.Cache}
+		handler(buf, r)
+		if buf.passedThrough {
+			return
+		}
+		status, h := buf.statusCode(), buf.header
+		if status == http.StatusOK {
+			setCacheControl(h, op.Cache)
+			if op.ETag && h.Get("ETag") == "" {
+				h.Set("ETag", bodyETag(buf.body.Bytes()))
+			}
+		}
+		dst := w.Header()
+		copyHeader(dst, h)
+		if status == http.StatusOK && notModified(r, h) {
+			dst.Del("Content-Type")
+			dst.Del("Content-Length")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.WriteHeader(status)
+		w.Write(buf.body.Bytes())
+	}
+}
+
+// setCacheControl sets the Cache-Control header of operations declaring cache, unless the handler set it.
+func setCacheControl(h http.Header, cache string) {
+	if cache != "" && h.Get("Cache-Control") == "" {
+		h.Set("Cache-Control", cache)
+	}
+}
+
+// notModified reports whether the conditional GET r matches the response headers h.
+func notModified(r *http.Request, h http.Header) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return matchesETag(inm, h.Get("ETag"), true)
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	modified, err := http.ParseTime(h.Get("Last-Modified"))
+	return err == nil && !modified.After(since)
+}
+
+// ErrPreconditionFailed is returned by typed handlers whose stored resource does not match
+// the If-Match precondition of the request.
+var ErrPreconditionFailed = NewError(http.StatusPreconditionFailed, "precondition failed")
+
+type ifMatchKey struct{}
+
+// IfMatchFromContext returns the If-Match header of a PUT, PATCH or DELETE request of an
+// operation with etag = true. Handlers compare it with the ETag of the stored resource in the
+// same transaction as the write, using MatchesIfMatch or e.g. a conditional UPDATE.
+func IfMatchFromContext(ctx context.Context) (string, bool) {
+	ifMatch, ok := ctx.Value(ifMatchKey{}).(string)
+	return ifMatch, ok
+}
+
+// MatchesIfMatch reports whether etag, the current ETag of an existing resource, satisfies the
+// If-Match header value ifMatch.
+func MatchesIfMatch(ifMatch, etag string) bool {
+	return matchesETag(ifMatch, etag, false)
+}
+
+// ETagResolver returns the current ETag of the resources written by PUT, PATCH and DELETE
+// operations with etag = true, so their If-Match preconditions are checked before the handler runs.
+type ETagResolver interface {
+	// CurrentETag returns the ETag of the resource r writes to, or "" when it does not exist.
+	CurrentETag(ctx context.Context, r *http.Request) (string, error)
+}
+
+// ETagResolverFunc adapts a function to the ETagResolver interface.
+type ETagResolverFunc func(ctx context.Context, r *http.Request) (string, error)
+
+// CurrentETag calls f(ctx, r).
+func (f ETagResolverFunc) CurrentETag(ctx context.Context, r *http.Request) (string, error) {
+	return f(ctx, r)
+}
+
+// SetETagResolver sets the resolver checking If-Match preconditions. Without one, handlers check
+// them with IfMatchFromContext. Pass nil to remove it.
+func (reg *Registry) SetETagResolver(resolver ETagResolver) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.etagResolver = resolver
+}
+
+// SetETagResolver sets the ETag resolver of the default registry.
+func SetETagResolver(resolver ETagResolver) {
+	defaultRegistry.SetETagResolver(resolver)
+}
+
+// withIfMatch passes the If-Match precondition of PUT, PATCH and DELETE operations declaring etag
+// to the handler, see IfMatchFromContext. When the registry has an ETagResolver, requests whose
+// precondition does not match the current ETag get 412 Precondition Failed without running the
+// handler, and resolver errors are mapped to a status as for typed handlers. The check and the
+// write are not atomic, handlers needing a guarantee compare the precondition in the same
+// transaction as the write.
+func (reg *Registry) withIfMatch(handler http.HandlerFunc, op *RestOperation) http.HandlerFunc {
+	if !op.ETag || op.Method == http.MethodGet {
+		return handler
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		ifMatch := r.Header.Get("If-Match")
+		if ifMatch == "" {
+			handler(w, r)
+			return
+		}
+		reg.mu.RLock()
+		resolver := reg.etagResolver
+		reg.mu.RUnlock()
+		if resolver != nil {
+			current, err := resolver.CurrentETag(r.Context(), r)
+			if err != nil {
+				writeError(w, err)
+				return
+			}
+			if !matchesETag(ifMatch, current, false) {
+				http.Error(w, http.StatusText(http.StatusPreconditionFailed), http.StatusPreconditionFailed)
+				return
+			}
+		}
+		handler(w, r.WithContext(context.WithValue(r.Context(), ifMatchKey{}, ifMatch)))
+	}
+}
+
+// bodyETag returns a strong ETag hashed from body.
+func bodyETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// matchesETag reports whether etag is listed in the If-Match or If-None-Match header value.
+// Weak comparison ignores W/ prefixes, strong comparison never matches weak tags. "*" matches
+// any existing resource.
+func matchesETag(header, etag string, weak bool) bool {
+	if etag == "" {
+		return false
+	}
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if weak {
+			if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		} else if candidate == etag && !strings.HasPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// bufferedResponse holds a response until it is complete, or until it exceeds maxSize or is
+// flushed, from which point it passes the response through to w.
+type bufferedResponse struct {
+	w             http.ResponseWriter
+	header        http.Header
+	body          bytes.Buffer
+	status        int
+	maxSize       int
+	cache         string
+	passedThrough bool
+}
+
+func (b *bufferedResponse) Header() http.Header {
+	if b.passedThrough {
+		return b.w.Header()
+	}
+	return b.header
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+	if !b.passedThrough && b.body.Len()+len(p) > b.maxSize {
+		b.passThrough()
+	}
+	if b.passedThrough {
+		return b.w.Write(p)
+	}
+	return b.body.Write(p)
+}
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	if b.status == 0 {
+		b.status = status
+	}
+}
+
+// Flush passes the response through, so streaming handlers keep working without an ETag.
+func (b *bufferedResponse) Flush() {
+	b.passThrough()
+	http.NewResponseController(b.w).Flush()
+}
+
+// Unwrap returns the underlying response writer, for http.ResponseController.
+func (b *bufferedResponse) Unwrap() http.ResponseWriter {
+	return b.w
+}
+
+// passThrough sends the buffered status, headers and body, with the Cache-Control header of
+// successful responses but no generated ETag, and lets later writes through unbuffered.
+func (b *bufferedResponse) passThrough() {
+	if b.passedThrough {
+		return
+	}
+	b.passedThrough = true
+	status := b.statusCode()
+	if status == http.StatusOK {
+		setCacheControl(b.header, b.cache)
+	}
+	copyHeader(b.w.Header(), b.header)
+	b.w.WriteHeader(status)
+	b.w.Write(b.body.Bytes())
+	b.body.Reset()
+}
+
+func (b *bufferedResponse) statusCode() int {
+	if b.status == 0 {
+		return http.StatusOK
+	}
+	return b.status
+}
@@ -0,0 +1,216 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type docsHandler struct {
+	docs map[string]string
+}
+
+// @RestOperation( method = "GET", path = "/docs/{id}", disableAuth = true, cache = "public, max-age=60", etag = true )
+func (h *docsHandler) GetDoc(w http.ResponseWriter, r *http.Request) {
+	doc, ok := h.docs[PathParam(r, "id")]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte(doc))
+}
+
+// @RestOperation( method = "PUT", path = "/docs/{name}", disableAuth = true, etag = true )
+func (h *docsHandler) PutDoc(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	h.docs[PathParam(r, "name")] = string(body)
+}
+
+// @RestOperation( method = "DELETE", path = "/docs/{id}", disableAuth = true, etag = true )
+func (h *docsHandler) DeleteDoc(w http.ResponseWriter, r *http.Request) {
+	ifMatch, _ := IfMatchFromContext(r.Context())
+	w.Header().Set("X-If-Match", ifMatch)
+	delete(h.docs, PathParam(r, "id"))
+}
+
+// @RestOperation( method = "GET", path = "/feed", disableAuth = true, cache = "no-cache" )
+func (h *docsHandler) Feed(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Last-Modified", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Format(http.TimeFormat))
+	w.Write([]byte("feed"))
+}
+
+func TestParseCacheKeys(t *testing.T) {
+	op, err := ParseRestOperation(`@RestOperation( method = "GET", path = "/", cache = "public, max-age=60", etag = true )`)
+	require.NoError(t, err)
+	assert.Equal(t, "public, max-age=60", op.Cache)
+	assert.True(t, op.ETag)
+
+	_, err = ParseRestOperation(`@RestOperation( method = "POST", path = "/", cache = "no-store" )`)
+	assert.ErrorIs(t, err, ErrInvalidCache)
+	_, err = ParseRestOperation(`@RestOperation( method = "POST", path = "/", etag = true )`)
+	assert.ErrorIs(t, err, ErrInvalidETag)
+}
+
+func TestCaching(t *testing.T) {
+	handler := &docsHandler{docs: map[string]string{"readme": "hello"}}
+	router := mux.NewRouter()
+	require.NoError(t, NewRegistry().RegisterRoutes(router, handler, "./cache_test.go"))
+	conditional := func(method, path, header, value, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set(header, value)
+		return serveRequest(router, req)
+	}
+
+	res := serve(router, "/docs/readme")
+	etag := res.Header().Get("ETag")
+
+	t.Run("adds Cache-Control and ETag to GET responses", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, "hello", res.Body.String())
+		assert.Equal(t, "public, max-age=60", res.Header().Get("Cache-Control"))
+		assert.Equal(t, bodyETag([]byte("hello")), etag)
+	})
+
+	t.Run("answers If-None-Match with 304", func(t *testing.T) {
+		res := conditional("GET", "/docs/readme", "If-None-Match", `"other", W/`+etag, "")
+		assert.Equal(t, http.StatusNotModified, res.Code)
+		assert.Empty(t, res.Body.String())
+		assert.Equal(t, etag, res.Header().Get("ETag"))
+		assert.Empty(t, res.Header().Get("Content-Type"))
+		assert.Equal(t, http.StatusOK, conditional("GET", "/docs/readme", "If-None-Match", `"other"`, "").Code)
+	})
+
+	t.Run("answers If-Modified-Since with 304", func(t *testing.T) {
+		res := conditional("GET", "/feed", "If-Modified-Since", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC).Format(http.TimeFormat), "")
+		assert.Equal(t, http.StatusNotModified, res.Code)
+		assert.Equal(t, "no-cache", res.Header().Get("Cache-Control"))
+		res = conditional("GET", "/feed", "If-Modified-Since", time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC).Format(http.TimeFormat), "")
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Empty(t, res.Header().Get("ETag"))
+	})
+
+	t.Run("does not cache errors", func(t *testing.T) {
+		res := serve(router, "/docs/missing")
+		assert.Equal(t, http.StatusNotFound, res.Code)
+		assert.Empty(t, res.Header().Get("Cache-Control"))
+		assert.Empty(t, res.Header().Get("ETag"))
+	})
+
+	t.Run("passes the precondition to the handler", func(t *testing.T) {
+		handler.docs["old"] = "bye"
+		res := conditional("DELETE", "/docs/old", "If-Match", `"any"`, "")
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, `"any"`, res.Header().Get("X-If-Match"))
+		assert.NotContains(t, handler.docs, "old")
+		assert.True(t, MatchesIfMatch(etag+`, "other"`, etag))
+		assert.False(t, MatchesIfMatch("W/"+etag, "W/"+etag))
+	})
+}
+
+func TestETagResolver(t *testing.T) {
+	handler := &docsHandler{docs: map[string]string{"readme": "hello"}}
+	registry := NewRegistry()
+	var calls int
+	registry.SetETagResolver(ETagResolverFunc(func(ctx context.Context, r *http.Request) (string, error) {
+		calls++
+		name := PathParam(r, "name") + PathParam(r, "id")
+		if name == "broken" {
+			return "", NewError(http.StatusServiceUnavailable, "store unavailable")
+		}
+		doc, ok := handler.docs[name]
+		if !ok {
+			return "", nil
+		}
+		return bodyETag([]byte(doc)), nil
+	}))
+	router := mux.NewRouter()
+	require.NoError(t, registry.RegisterRoutes(router, handler, "./cache_test.go"))
+	put := func(path, ifMatch, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PUT", path, strings.NewReader(body))
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		return serveRequest(router, req)
+	}
+	etag := serve(router, "/docs/readme").Header().Get("ETag")
+
+	t.Run("checks If-Match before the handler", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, put("/docs/readme", etag, "v2").Code)
+		assert.Equal(t, "v2", handler.docs["readme"])
+		assert.Equal(t, http.StatusPreconditionFailed, put("/docs/readme", etag, "v3").Code)
+		assert.Equal(t, "v2", handler.docs["readme"])
+	})
+
+	t.Run("fails If-Match for missing resources", func(t *testing.T) {
+		assert.Equal(t, http.StatusPreconditionFailed, put("/docs/new", "*", "v1").Code)
+		assert.Equal(t, http.StatusOK, put("/docs/new", "", "v1").Code)
+		assert.Equal(t, http.StatusOK, put("/docs/new", "*", "v2").Code)
+	})
+
+	t.Run("maps resolver errors to a status", func(t *testing.T) {
+		assert.Equal(t, http.StatusServiceUnavailable, put("/docs/broken", "*", "v1").Code)
+		assert.NotContains(t, handler.docs, "broken")
+	})
+
+	t.Run("does not resolve requests without If-Match or GET requests", func(t *testing.T) {
+		calls = 0
+		put("/docs/readme", "", "v4")
+		serve(router, "/docs/readme")
+		assert.Zero(t, calls)
+	})
+}
+
+func TestCachingPassThrough(t *testing.T) {
+	register := func(t *testing.T, handler http.HandlerFunc) *mux.Router {
+		registry := NewRegistry()
+		registry.SetMaxCachedResponseSize(8)
+		op, err := ParseRestOperation(`@RestOperation( method = "GET", path = "/stream", disableAuth = true, cache = "no-cache", etag = true )`)
+		require.NoError(t, err)
+		router := mux.NewRouter()
+		require.NoError(t, registry.RegisterRoute(router, &RouteMetadata{Operation: op, HandlerMethod: "Stream", Package: "http"}, handler))
+		return router
+	}
+
+	t.Run("passes responses over the maximum size through without an ETag", func(t *testing.T) {
+		router := register(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("hello "))
+			w.Write([]byte("world"))
+		})
+		req := httptest.NewRequest("GET", "/stream", nil)
+		req.Header.Set("If-None-Match", "*")
+		res := serveRequest(router, req)
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, "hello world", res.Body.String())
+		assert.Equal(t, "no-cache", res.Header().Get("Cache-Control"))
+		assert.Empty(t, res.Header().Get("ETag"))
+	})
+
+	t.Run("passes flushed responses through", func(t *testing.T) {
+		router := register(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("a"))
+			w.(http.Flusher).Flush()
+			w.Write([]byte("b"))
+		})
+		res := serve(router, "/stream")
+		assert.True(t, res.Flushed)
+		assert.Equal(t, "ab", res.Body.String())
+		assert.Empty(t, res.Header().Get("ETag"))
+	})
+
+	t.Run("buffers responses up to the maximum size", func(t *testing.T) {
+		router := register(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("12345678"))
+		})
+		assert.Equal(t, bodyETag([]byte("12345678")), serve(router, "/stream").Header().Get("ETag"))
+	})
+}
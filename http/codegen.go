@@ -0,0 +1,227 @@
+package http
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// GeneratedFileName is the default name of the file written by GenerateRegistration.
+const GeneratedFileName = "zz_routes.go"
+
+var restImportPath = reflect.TypeOf(RouteMetadata{}).PkgPath()
+
+// GenerateRegistration writes Go source for package pkg that registers every annotated
+// method in routes as a direct method value, adapting typed handlers with TypedHandler.
+// For each handler type T it emits
+//
+//	func RegisterTRoutes(router *mux.Router, h *T) error
+//	func RegisterTRoutesWith(registry *rest.Registry, router *mux.Router, h *T) error
+//	func RegisterTRoutesTo(registry *rest.Registry, target rest.RouteTarget, h *T) error
+//
+// and, when top-level functions are annotated,
+//
+//	func RegisterFunctionRoutes(router *mux.Router) error
+//	func RegisterFunctionRoutesWith(registry *rest.Registry, router *mux.Router) error
+//	func RegisterFunctionRoutesTo(registry *rest.Registry, target rest.RouteTarget) error
+//
+// so binaries no longer need handler sources or reflection at runtime. The routes of each
+// function are registered in one RegisterBoundRoutesTo call, so none is registered when one
+// fails. Line numbers are left out, they would go stale with every edit of the sources.
+func GenerateRegistration(w io.Writer, pkg string, routes []*RouteMetadata) error {
+	byType := make(map[string][]*RouteMetadata)
+	var funcs []*RouteMetadata
+	for _, route := range routes {
+		if route.Package != pkg {
+			return fmt.Errorf("route %s belongs to package %s, not %s", route.RouteName(), route.Package, pkg)
+		}
+		if route.Signature != "" && !route.IsTyped() && !route.isHandlerFunc() {
+			return fmt.Errorf("route %s: handler %s is not a http.HandlerFunc or func(context.Context, *T) (*R, error)", route.RouteName(), route.Signature)
+		}
+		if route.HandlerType == "" {
+			funcs = append(funcs, route)
+			continue
+		}
+		byType[route.HandlerType] = append(byType[route.HandlerType], route)
+	}
+	if len(byType) == 0 && len(funcs) == 0 {
+		return errors.New("no annotated handler methods found")
+	}
+	types := make([]string, 0, len(byType))
+	for name := range byType {
+		types = append(types, name)
+	}
+	sort.Strings(types)
+
+	lit := &literalWriter{imports: map[string]bool{}}
+	var body bytes.Buffer
+	for _, typeName := range types {
+		funcName := "Register" + exportName(typeName) + "Routes"
+		fmt.Fprintf(&body, "\n// %s registers the annotated routes of %s on router using the default registry.\n", funcName, typeName)
+		fmt.Fprintf(&body, "func %s(router *mux.Router, h *%s) error {\nreturn %sWith(rest.DefaultRegistry(), router, h)\n}\n", funcName, typeName, funcName)
+		fmt.Fprintf(&body, "\n// %sWith registers the annotated routes of %s on router using registry.\n", funcName, typeName)
+		fmt.Fprintf(&body, "func %sWith(registry *rest.Registry, router *mux.Router, h *%s) error {\nreturn %sTo(registry, rest.MuxTarget(router), h)\n}\n", funcName, typeName, funcName)
+		fmt.Fprintf(&body, "\n// %sTo registers the annotated routes of %s on target using registry.\n", funcName, typeName)
+		fmt.Fprintf(&body, "func %sTo(registry *rest.Registry, target rest.RouteTarget, h *%s) error {\n", funcName, typeName)
+		writeRegistrations(&body, lit, byType[typeName], "h.")
+	}
+	if len(funcs) > 0 {
+		body.WriteString("\n// RegisterFunctionRoutes registers the annotated top-level functions on router using the default registry.\n")
+		body.WriteString("func RegisterFunctionRoutes(router *mux.Router) error {\nreturn RegisterFunctionRoutesWith(rest.DefaultRegistry(), router)\n}\n")
+		body.WriteString("\n// RegisterFunctionRoutesWith registers the annotated top-level functions on router using registry.\n")
+		body.WriteString("func RegisterFunctionRoutesWith(registry *rest.Registry, router *mux.Router) error {\nreturn RegisterFunctionRoutesTo(registry, rest.MuxTarget(router))\n}\n")
+		body.WriteString("\n// RegisterFunctionRoutesTo registers the annotated top-level functions on target using registry.\n")
+		body.WriteString("func RegisterFunctionRoutesTo(registry *rest.Registry, target rest.RouteTarget) error {\n")
+		writeRegistrations(&body, lit, funcs, "")
+	}
+
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "// Code generated by restgen. DO NOT EDIT.\n\npackage %s\n\nimport (\n", pkg)
+	// Standard library imports come first, in their own group.
+	var std []string
+	imports := []string{"github.com/gorilla/mux"}
+	for path := range lit.imports {
+		if strings.Contains(strings.Split(path, "/")[0], ".") {
+			imports = append(imports, path)
+		} else {
+			std = append(std, path)
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(imports)
+	for _, path := range std {
+		fmt.Fprintf(&src, "%q\n", path)
+	}
+	if len(std) > 0 {
+		src.WriteString("\n")
+	}
+	for _, path := range imports {
+		fmt.Fprintf(&src, "%q\n", path)
+	}
+	fmt.Fprintf(&src, "\nrest %q\n)\n", restImportPath)
+	src.Write(body.Bytes())
+	formatted, err := format.Source(src.Bytes())
+	if err != nil {
+		return fmt.Errorf("failed to format generated code: %w", err)
+	}
+	_, err = w.Write(formatted)
+	return err
+}
+
+// writeRegistrations writes the body of a registration function: a single RegisterBoundRoutesTo
+// call with every route, without its line. prefix qualifies the handler, e.g. "h." for methods.
+func writeRegistrations(body *bytes.Buffer, lit *literalWriter, routes []*RouteMetadata, prefix string) {
+	body.WriteString("return registry.RegisterBoundRoutesTo(target,\n")
+	for _, route := range routes {
+		handler := prefix + route.HandlerMethod
+		if route.IsTyped() {
+			handler = "rest.TypedHandler(" + handler + ")"
+		}
+		unpositioned := *route
+		unpositioned.Line = 0
+		fmt.Fprintf(body, "rest.BoundRoute{Route: %s, Handler: %s},\n", lit.write(reflect.ValueOf(&unpositioned)), handler)
+	}
+	body.WriteString(")\n}\n")
+}
+
+// literalWriter renders values as Go composite literals, omitting zero fields.
+type literalWriter struct {
+	imports map[string]bool
+}
+
+func (l *literalWriter) write(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return "nil"
+		}
+		return "&" + l.write(v.Elem())
+	case reflect.Struct:
+		var fields []string
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() || v.Field(i).IsZero() {
+				continue
+			}
+			fields = append(fields, field.Name+": "+l.write(v.Field(i)))
+		}
+		return l.typeName(v.Type()) + "{" + strings.Join(fields, ", ") + "}"
+	case reflect.Slice:
+		if v.IsNil() {
+			return "nil"
+		}
+		elems := make([]string, v.Len())
+		for i := range elems {
+			elems[i] = l.write(v.Index(i))
+		}
+		return l.typeName(v.Type()) + "{" + strings.Join(elems, ", ") + "}"
+	case reflect.Map:
+		if v.IsNil() {
+			return "nil"
+		}
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+		elems := make([]string, len(keys))
+		for i, key := range keys {
+			elems[i] = l.write(key) + ": " + l.write(v.MapIndex(key))
+		}
+		return l.typeName(v.Type()) + "{" + strings.Join(elems, ", ") + "}"
+	case reflect.String:
+		return l.convert(v, strconv.Quote(v.String()))
+	case reflect.Bool:
+		return l.convert(v, strconv.FormatBool(v.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return l.convert(v, strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return l.convert(v, strconv.FormatUint(v.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		return l.convert(v, strconv.FormatFloat(v.Float(), 'g', -1, 64))
+	case reflect.Interface:
+		if v.IsNil() {
+			return "nil"
+		}
+		return l.write(v.Elem())
+	}
+	panic(fmt.Sprintf("restgen: unsupported kind %s", v.Kind()))
+}
+
+// convert wraps basic literals of named types in a conversion, e.g. time.Duration(5).
+func (l *literalWriter) convert(v reflect.Value, lit string) string {
+	if v.Type().PkgPath() == "" {
+		return lit
+	}
+	return l.typeName(v.Type()) + "(" + lit + ")"
+}
+
+func (l *literalWriter) typeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return "*" + l.typeName(t.Elem())
+	case reflect.Slice:
+		return "[]" + l.typeName(t.Elem())
+	case reflect.Map:
+		return "map[" + l.typeName(t.Key()) + "]" + l.typeName(t.Elem())
+	}
+	switch t.PkgPath() {
+	case "":
+		return t.String()
+	case restImportPath:
+		return "rest." + t.Name()
+	default:
+		l.imports[t.PkgPath()] = true
+		return t.String()
+	}
+}
+
+func exportName(name string) string {
+	r := []rune(name)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
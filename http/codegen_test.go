@@ -0,0 +1,97 @@
+package http
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateRegistration(t *testing.T) {
+	t.Run("generates registration function per handler type", func(t *testing.T) {
+		content := `package api
+
+// @RestOperation( method = "GET", path = "/users/{id}", middlewares = ["auth"], timeout = 10 )
+func (s *Service) GetUser(w http.ResponseWriter, r *http.Request) {}
+
+// @RestOperation( method = "POST", path = "/users", disableAuth = true )
+func (s *Service) CreateUser(w http.ResponseWriter, r *http.Request) {}
+
+// @RestOperation( method = "GET", path = "/health" )
+func (h *health) Check(w http.ResponseWriter, r *http.Request) {}
+
+type Service struct{}
+type health struct{}
+`
+		tmpFile := createTempFile(t, content)
+		defer os.Remove(tmpFile)
+		routes, err := ParseRouteMetadata(tmpFile)
+		require.NoError(t, err)
+		var buf bytes.Buffer
+		err = GenerateRegistration(&buf, "api", routes)
+		require.NoError(t, err)
+		src := buf.String()
+		assert.Contains(t, src, "// Code generated by restgen. DO NOT EDIT.")
+		assert.Contains(t, src, "package api")
+		assert.Contains(t, src, "func RegisterServiceRoutes(router *mux.Router, h *Service) error {")
+		assert.Contains(t, src, "func RegisterHealthRoutes(router *mux.Router, h *health) error {")
+		assert.Contains(t, src, "func RegisterServiceRoutesTo(registry *rest.Registry, target rest.RouteTarget, h *Service) error {")
+		assert.Contains(t, src, `&rest.RestOperation{Method: "GET", Path: "/users/{id}", Middlewares: []string{"auth"}, Timeout: 10}`)
+		assert.Contains(t, src, `&rest.RestOperation{Method: "POST", Path: "/users", DisableAuth: true}`)
+		assert.Contains(t, src, "return registry.RegisterBoundRoutesTo(target,")
+		assert.Contains(t, src, "Handler: h.GetUser},")
+		assert.NotContains(t, src, "Line:")
+		assert.NotContains(t, src, "errors.Join")
+		assert.NotContains(t, src, "reflect")
+	})
+
+	t.Run("generates registration function for top-level functions", func(t *testing.T) {
+		content := `package api
+
+// @RestOperation( method = "GET", path = "/health" )
+func Health(w http.ResponseWriter, r *http.Request) {}
+
+// @RestOperation( method = "GET", path = "/items/{id}" )
+func getItem(ctx context.Context, req *ItemRequest) (*Item, error) {}
+`
+		tmpFile := createTempFile(t, content)
+		routes, err := ParseRouteMetadata(tmpFile)
+		require.NoError(t, err)
+		var buf bytes.Buffer
+		require.NoError(t, GenerateRegistration(&buf, "api", routes))
+		src := buf.String()
+		assert.Contains(t, src, "func RegisterFunctionRoutes(router *mux.Router) error {")
+		assert.Contains(t, src, "func RegisterFunctionRoutesWith(registry *rest.Registry, router *mux.Router) error {")
+		assert.Contains(t, src, "Handler: Health},")
+		assert.Contains(t, src, "Handler: rest.TypedHandler(getItem)},")
+	})
+
+	t.Run("returns error when there are no handler methods", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := GenerateRegistration(&buf, "api", nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("returns error for routes of another package", func(t *testing.T) {
+		routes := []*RouteMetadata{{Operation: &RestOperation{Method: "GET", Path: "/"}, HandlerMethod: "Get", HandlerType: "Service", Package: "other"}}
+		var buf bytes.Buffer
+		err := GenerateRegistration(&buf, "api", routes)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "belongs to package other")
+	})
+
+	t.Run("returns error for unsupported handler signatures", func(t *testing.T) {
+		routes := []*RouteMetadata{{
+			Operation:     &RestOperation{Method: "GET", Path: "/"},
+			HandlerMethod: "Get",
+			HandlerType:   "Service",
+			Package:       "api",
+			Signature:     "func(ctx context.Context, req *Request) (Response, error)",
+		}}
+		var buf bytes.Buffer
+		err := GenerateRegistration(&buf, "api", routes)
+		assert.ErrorContains(t, err, "is not a http.HandlerFunc or func(context.Context, *T) (*R, error)")
+	})
+}
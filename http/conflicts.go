@@ -0,0 +1,209 @@
+package http
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// registeredRoute is a route already present on a router, in matching order.
+type registeredRoute struct {
+	name   string
+	method string
+	path   string
+	match  string
+}
+
+// routerRoutes lists the routes of router in the order gorilla mux matches them, with the
+// matchers of routes added through MuxTarget.
+func routerRoutes(router *mux.Router) []registeredRoute {
+	var routes []registeredRoute
+	if router == nil {
+		return routes
+	}
+	router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		var match string
+		if handler, ok := route.GetHandler().(matchingHandler); ok {
+			match = handler.match
+		}
+		methods, _ := route.GetMethods()
+		for _, method := range methods {
+			routes = append(routes, registeredRoute{name: route.GetName(), method: method, path: path, match: match})
+		}
+		return nil
+	})
+	return routes
+}
+
+// sortByOrder sorts routes by the order key of their operation, keeping the declaration
+// order of routes with the same order.
+func sortByOrder(bound []boundRoute) {
+	sort.SliceStable(bound, func(i, j int) bool {
+		return bound[i].route.Operation.Order < bound[j].route.Operation.Order
+	})
+}
+
+// checkConflicts compares route with the routes matched before it, using the patterns
+// registered on routers. A route with the same method, matchers and an equivalent path,
+// ignoring variable names, is an error. Earlier routes matching every path of route shadow it and
+// are reported as warnings.
+func checkConflicts(existing []registeredRoute, route *RouteMetadata) (warnings []error, err error) {
+	method, path := route.Operation.Method, route.Operation.Path
+	pattern := routePattern(path)
+	normalized := normalizePath(pattern)
+	match := route.Operation.Match().key()
+	for _, other := range existing {
+		// Routes with other matchers do not overlap, unless the earlier one has none.
+		if other.method != method || other.match != match && other.match != "" {
+			continue
+		}
+		if normalizePath(other.path) == normalized && other.match == match {
+			return nil, fmt.Errorf("duplicate route %s %s, already registered by %s", method, path, other.name)
+		}
+		if pathCovers(other.path, pattern) {
+			warnings = append(warnings, fmt.Errorf("route %s %s is shadowed by %s %s registered by %s, use order to register it first", method, path, other.method, other.path, other.name))
+		}
+	}
+	return warnings, nil
+}
+
+// normalizePath removes variable names from a path template, so "/person/{uid}" and
+// "/person/{id}" compare equal while "/person/{id:[0-9]+}" does not.
+func normalizePath(path string) string {
+	var b strings.Builder
+	for _, part := range splitTemplate(path) {
+		if name, pattern, ok := pathVar(part); ok && name != "" {
+			if pattern == "" {
+				b.WriteString("{}")
+			} else {
+				b.WriteString("{:" + pattern + "}")
+			}
+			continue
+		}
+		b.WriteString(part)
+	}
+	return b.String()
+}
+
+// splitTemplate splits a path template into literal text and {variable} parts.
+func splitTemplate(path string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i := 0; i < len(path); i++ {
+		switch path[i] {
+		case '{':
+			if depth == 0 {
+				if i > start {
+					parts = append(parts, path[start:i])
+				}
+				start = i
+			}
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				parts = append(parts, path[start:i+1])
+				start = i + 1
+			}
+		}
+	}
+	if start < len(path) {
+		parts = append(parts, path[start:])
+	}
+	return parts
+}
+
+// splitSegments splits a path template on the slashes outside of its {variable} parts.
+func splitSegments(path string) []string {
+	var segments []string
+	depth, start := 0, 0
+	for i := 0; i < len(path); i++ {
+		switch path[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case '/':
+			if depth == 0 {
+				segments = append(segments, path[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(segments, path[start:])
+}
+
+// templateRegexp compiles a path template to the regular expression of the paths it matches.
+// Variables without a pattern match a single segment.
+func templateRegexp(path string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for _, part := range splitTemplate(path) {
+		if _, pattern, ok := pathVar(part); ok {
+			if pattern == "" {
+				pattern = "[^/]+"
+			}
+			b.WriteString("(?:" + pattern + ")")
+			continue
+		}
+		b.WriteString(regexp.QuoteMeta(part))
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+// pathVar splits a {name} or {name:pattern} variable.
+func pathVar(part string) (name, pattern string, ok bool) {
+	if !strings.HasPrefix(part, "{") || !strings.HasSuffix(part, "}") {
+		return "", "", false
+	}
+	name, pattern, _ = strings.Cut(part[1:len(part)-1], ":")
+	return strings.TrimSpace(name), pattern, true
+}
+
+// pathCovers reports whether the template general matches every path matched by specific.
+// Literal paths are matched against general as a whole, since its patterns may contain "/".
+func pathCovers(general, specific string) bool {
+	if !strings.Contains(specific, "{") {
+		re, err := templateRegexp(general)
+		return err == nil && re.MatchString(specific)
+	}
+	g, s := splitSegments(general), splitSegments(specific)
+	if len(g) != len(s) {
+		return false
+	}
+	for i := range g {
+		if !segmentCovers(g[i], s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func segmentCovers(general, specific string) bool {
+	if normalizePath(general) == normalizePath(specific) {
+		return true
+	}
+	_, pattern, ok := pathVar(general)
+	if !ok || len(splitTemplate(general)) != 1 {
+		return false
+	}
+	if pattern == "" {
+		return specific != ""
+	}
+	if _, specificPattern, ok := pathVar(specific); ok {
+		return specificPattern == pattern
+	}
+	if strings.Contains(specific, "{") {
+		return false
+	}
+	re, err := regexp.Compile("^(?:" + pattern + ")$")
+	return err == nil && re.MatchString(specific)
+}
@@ -0,0 +1,110 @@
+package http
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type personRoutes struct{}
+
+func (h *personRoutes) Get(w http.ResponseWriter, r *http.Request) { w.Write([]byte("get")) }
+
+func (h *personRoutes) Me(w http.ResponseWriter, r *http.Request) { w.Write([]byte("me")) }
+
+func TestPathConflicts(t *testing.T) {
+	t.Run("normalizes variable names", func(t *testing.T) {
+		assert.Equal(t, "/person/{}/orders/{:[0-9]+}", normalizePath("/person/{uid}/orders/{id:[0-9]+}"))
+		assert.Equal(t, "/files/{}.{:[a-z]{2,3}}", normalizePath("/files/{name}.{ext:[a-z]{2,3}}"))
+	})
+
+	t.Run("detects covering templates", func(t *testing.T) {
+		assert.True(t, pathCovers("/person/{uid}", "/person/me"))
+		assert.True(t, pathCovers("/person/{uid}", "/person/{id:[0-9]+}"))
+		assert.True(t, pathCovers("/person/{id:[0-9]+}", "/person/42"))
+		assert.False(t, pathCovers("/person/{id:[0-9]+}", "/person/me"))
+		assert.False(t, pathCovers("/person/me", "/person/{uid}"))
+		assert.False(t, pathCovers("/person/{uid}", "/person/{uid}/orders"))
+	})
+
+	t.Run("keeps patterns containing slashes in one segment", func(t *testing.T) {
+		assert.Equal(t, []string{"", "files", "{path:[a-z]+/[a-z]+}", "raw"}, splitSegments("/files/{path:[a-z]+/[a-z]+}/raw"))
+		assert.True(t, pathCovers("/files/{path:[a-z]+/[a-z]+}", "/files/a/b"))
+		assert.False(t, pathCovers("/files/{path:[a-z]+/[a-z]+}", "/files/a"))
+		assert.False(t, pathCovers("/files/{dir}/{name}", "/files/{p:a|b/c}"))
+		assert.True(t, pathCovers("/files/{dir}/{name}", "/files/{dir:[a-z]+}/{name}"))
+	})
+}
+
+func TestRouteConflicts(t *testing.T) {
+	loggedRegistry := func() (*Registry, *bytes.Buffer) {
+		var buf bytes.Buffer
+		registry := NewRegistry()
+		registry.SetLogger(slog.New(slog.NewTextHandler(&buf, nil)))
+		return registry, &buf
+	}
+
+	t.Run("rejects equivalent paths with different variable names", func(t *testing.T) {
+		file := createTempFile(t, `package http
+
+// @RestOperation( method = "GET", path = "/person/{uid}", disableAuth = true )
+func (h *personRoutes) Get(w http.ResponseWriter, r *http.Request) {}
+
+// @RestOperation( method = "GET", path = "/person/{id}", disableAuth = true )
+func (h *personRoutes) Me(w http.ResponseWriter, r *http.Request) {}
+`)
+		err := NewRegistry().ValidateRoutes(&personRoutes{}, file)
+		assert.ErrorContains(t, err, "duplicate route GET /person/{id}, already registered by http.personRoutes.Get")
+	})
+
+	t.Run("warns about shadowed routes", func(t *testing.T) {
+		registry, logs := loggedRegistry()
+		file := createTempFile(t, `package http
+
+// @RestOperation( method = "GET", path = "/person/{uid}", disableAuth = true )
+func (h *personRoutes) Get(w http.ResponseWriter, r *http.Request) {}
+
+// @RestOperation( method = "GET", path = "/person/me", disableAuth = true )
+func (h *personRoutes) Me(w http.ResponseWriter, r *http.Request) {}
+`)
+		router := mux.NewRouter()
+		require.NoError(t, registry.RegisterRoutes(router, &personRoutes{}, file))
+		assert.Contains(t, logs.String(), `level=WARN msg="shadowed route" error="`+file+":6: http.personRoutes.Me: route GET /person/me is shadowed by GET /person/{uid}")
+		assert.Contains(t, logs.String(), `level=INFO msg="registered route" route=http.personRoutes.Me method=GET path=/person/me`)
+	})
+
+	t.Run("order registers overlapping routes first", func(t *testing.T) {
+		registry, logs := loggedRegistry()
+		file := createTempFile(t, `package http
+
+// @RestOperation( method = "GET", path = "/person/{uid}", disableAuth = true )
+func (h *personRoutes) Get(w http.ResponseWriter, r *http.Request) {}
+
+// @RestOperation( method = "GET", path = "/person/me", disableAuth = true, order = -1 )
+func (h *personRoutes) Me(w http.ResponseWriter, r *http.Request) {}
+`)
+		router := mux.NewRouter()
+		require.NoError(t, registry.RegisterRoutes(router, &personRoutes{}, file))
+		assert.NotContains(t, logs.String(), "level=WARN")
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, httptest.NewRequest("GET", "/person/me", nil))
+		assert.Equal(t, "me", res.Body.String())
+		res = httptest.NewRecorder()
+		router.ServeHTTP(res, httptest.NewRequest("GET", "/person/bill", nil))
+		assert.Equal(t, "get", res.Body.String())
+		assert.Equal(t, "http.personRoutes.Me", registry.Routes()[0].Name)
+	})
+
+	t.Run("different methods do not conflict", func(t *testing.T) {
+		existing := []registeredRoute{{name: "get", method: "GET", path: "/person/{uid}"}}
+		warnings, err := checkConflicts(existing, &RouteMetadata{Operation: &RestOperation{Method: "POST", Path: "/person/{id}"}})
+		assert.NoError(t, err)
+		assert.Empty(t, warnings)
+	})
+}
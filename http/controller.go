@@ -0,0 +1,161 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strings"
+)
+
+// RestController holds the defaults declared by a @RestController annotation on a handler
+// type. They are merged into the @RestOperation of every method of that type:
+//
+//   - path is a prefix joined with the method path, which may then be empty;
+//   - middlewares run before the method's own, a reference listed by both runs once;
+//   - timeout, disableAuth, accessLog, disableAccessLog, disableMetrics and cors apply unless the
+//     method sets them itself.
+type RestController struct {
+	Path             string
+	Middlewares      []string
+	Timeout          int
+	DisableAuth      bool
+	AccessLog        bool
+	DisableAccessLog bool
+	DisableMetrics   bool
+	CORS             *CORS
+	set              map[string]bool
+}
+
+// ParseRestController parses a @RestController annotation string into a RestController struct.
+func ParseRestController(annotation string) (*RestController, error) {
+	ann, err := parseAnnotation(annotation, "RestController")
+	if err != nil {
+		return nil, err
+	}
+	return newRestController(ann)
+}
+
+// controllerKeys lists the @RestOperation parameters a controller may set.
+var controllerKeys = map[string]bool{"path": true, "middlewares": true, "timeout": true, "disableAuth": true, "accessLog": true, "disableAccessLog": true, "disableMetrics": true, "cors": true}
+
+func newRestController(ann *annotation) (*RestController, error) {
+	ctrl := &RestController{set: make(map[string]bool)}
+	// Values are parsed with the same rules as @RestOperation, then copied.
+	op := &RestOperation{}
+	for _, arg := range ann.args {
+		if !controllerKeys[arg.key] {
+			return nil, ann.errorAt(arg.offset, ErrUnknownKey, "unknown parameter %s", arg.key)
+		}
+		if err := op.set(arg); err != nil {
+			if errors.Is(err, ErrUnknownKey) {
+				return nil, ann.errorAt(arg.offset, err, "unknown parameter %s", arg.key)
+			}
+			return nil, ann.errorAt(arg.value.offset, err, "%v", err)
+		}
+		ctrl.set[arg.key] = true
+	}
+	ctrl.Path = op.Path
+	ctrl.Middlewares = op.Middlewares
+	ctrl.Timeout = op.Timeout
+	ctrl.DisableAuth = op.DisableAuth
+	ctrl.AccessLog = op.AccessLog
+	ctrl.DisableAccessLog = op.DisableAccessLog
+	ctrl.DisableMetrics = op.DisableMetrics
+	ctrl.CORS = op.CORS
+	if ctrl.Timeout < 0 {
+		return nil, ErrInvalidTimeout
+	}
+	return ctrl, nil
+}
+
+// apply merges the controller defaults into op. explicit reports whether the method
+// annotation set a parameter itself.
+func (c *RestController) apply(op *RestOperation, explicit func(key string) bool) {
+	op.Path = joinPath(c.Path, op.Path)
+	if len(c.Middlewares) > 0 {
+		merged := append([]string{}, c.Middlewares...)
+		for _, ref := range op.Middlewares {
+			if !containsString(merged, ref) {
+				merged = append(merged, ref)
+			}
+		}
+		op.Middlewares = merged
+	}
+	if c.set["timeout"] && !explicit("timeout") {
+		op.Timeout = c.Timeout
+	}
+	if c.set["disableAuth"] && !explicit("disableAuth") {
+		op.DisableAuth = c.DisableAuth
+	}
+	if c.set["accessLog"] && !explicit("accessLog") {
+		op.AccessLog = c.AccessLog
+	}
+	if c.set["disableAccessLog"] && !explicit("disableAccessLog") {
+		op.DisableAccessLog = c.DisableAccessLog
+	}
+	if c.set["disableMetrics"] && !explicit("disableMetrics") {
+		op.DisableMetrics = c.DisableMetrics
+	}
+	if c.set["cors"] && !explicit("cors") {
+		op.CORS = c.CORS
+	}
+}
+
+// joinPath joins a controller path prefix and an operation path. An operation path of "/" maps to
+// the prefix itself, as routers treat "/person" and "/person/" as different paths.
+func joinPath(prefix, path string) string {
+	if prefix == "" || path == "" {
+		return prefix + path
+	}
+	if path == "/" && prefix != "/" {
+		return strings.TrimSuffix(prefix, "/")
+	}
+	return strings.TrimSuffix(prefix, "/") + "/" + strings.TrimPrefix(path, "/")
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// parseControllers collects the @RestController annotations declared on types in files, by type name.
+func parseControllers(fset *token.FileSet, files []*ast.File) (map[string]*RestController, error) {
+	controllers := make(map[string]*RestController)
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				typeSpec := spec.(*ast.TypeSpec)
+				doc := typeSpec.Doc
+				if doc == nil && len(gen.Specs) == 1 {
+					doc = gen.Doc
+				}
+				if doc == nil {
+					continue
+				}
+				for _, comment := range doc.List {
+					if !strings.Contains(comment.Text, "@RestController") {
+						continue
+					}
+					if _, exists := controllers[typeSpec.Name.Name]; exists {
+						return nil, fmt.Errorf("duplicate @RestController on %s: %w", typeSpec.Name.Name, positionError(fset, comment, ErrInvalidFormat))
+					}
+					ctrl, err := ParseRestController(comment.Text)
+					if err != nil {
+						return nil, fmt.Errorf("failed to parse annotation on %s: %w", typeSpec.Name.Name, positionError(fset, comment, err))
+					}
+					controllers[typeSpec.Name.Name] = ctrl
+				}
+			}
+		}
+	}
+	return controllers, nil
+}
@@ -0,0 +1,156 @@
+package http
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRestController(t *testing.T) {
+	t.Run("parses controller defaults", func(t *testing.T) {
+		ctrl, err := ParseRestController(`@RestController( path = "/api/v1", middlewares = ["auth"], timeout = 10 )`)
+		require.NoError(t, err)
+		assert.Equal(t, "/api/v1", ctrl.Path)
+		assert.Equal(t, []string{"auth"}, ctrl.Middlewares)
+		assert.Equal(t, 10, ctrl.Timeout)
+	})
+
+	t.Run("rejects operation-only parameters", func(t *testing.T) {
+		_, err := ParseRestController(`@RestController( method = "GET" )`)
+		assert.ErrorIs(t, err, ErrUnknownKey)
+		_, err = ParseRestController(`@RestController( order = 1 )`)
+		assert.ErrorIs(t, err, ErrUnknownKey)
+	})
+
+	t.Run("rejects invalid value", func(t *testing.T) {
+		_, err := ParseRestController(`@RestController( timeout = "soon" )`)
+		assert.ErrorContains(t, err, "invalid timeout value")
+	})
+}
+
+func TestRestControllerMerge(t *testing.T) {
+	file := createTempFile(t, `package api
+
+// Users serves user resources.
+// @RestController( path = "/api/users/", middlewares = ["auth", "logging"], timeout = 10, disableAuth = true )
+type Users struct{}
+
+// @RestOperation( method = "GET" )
+func (u *Users) List() {}
+
+// @RestOperation( method = "GET", path = "{id}", middlewares = ["logging", "cache"], timeout = 5, disableAuth = false )
+func (u *Users) Get() {}
+
+// @RestOperation( method = "GET", path = "/health" )
+func (o *Other) Health() {}
+`)
+	routes, err := ParseRouteMetadata(file)
+	require.NoError(t, err)
+	require.Len(t, routes, 3)
+
+	t.Run("uses controller path and defaults when method omits them", func(t *testing.T) {
+		op := routes[0].Operation
+		assert.Equal(t, "/api/users/", op.Path)
+		assert.Equal(t, []string{"auth", "logging"}, op.Middlewares)
+		assert.Equal(t, 10, op.Timeout)
+		assert.True(t, op.DisableAuth)
+	})
+
+	t.Run("method values override and append", func(t *testing.T) {
+		op := routes[1].Operation
+		assert.Equal(t, "/api/users/{id}", op.Path)
+		assert.Equal(t, []string{"auth", "logging", "cache"}, op.Middlewares)
+		assert.Equal(t, 5, op.Timeout)
+		assert.False(t, op.DisableAuth)
+	})
+
+	t.Run("other types are unaffected", func(t *testing.T) {
+		op := routes[2].Operation
+		assert.Equal(t, "/health", op.Path)
+		assert.Empty(t, op.Middlewares)
+		assert.Zero(t, op.Timeout)
+	})
+
+	t.Run("requires path without controller", func(t *testing.T) {
+		file := createTempFile(t, `package api
+
+// @RestOperation( method = "GET" )
+func (u *Users) List() {}
+`)
+		_, err := ParseRouteMetadata(file)
+		assert.ErrorIs(t, err, ErrMissingPath)
+	})
+
+	t.Run("applies controller declared in another file of the package", func(t *testing.T) {
+		root := createTempModule(t, map[string]string{
+			"go.mod": "module example.com/svc\n\ngo 1.22\n",
+			"api/users.go": `package api
+
+// @RestController( path = "/users" )
+type Users struct{}
+`,
+			"api/users_routes.go": `package api
+
+// @RestOperation( method = "GET", path = "/{id}" )
+func (u *Users) Get() {}
+`,
+		})
+		routes, err := ParsePackages(filepath.Join(root, "api"))
+		require.NoError(t, err)
+		require.Len(t, routes, 1)
+		assert.Equal(t, "/users/{id}", routes[0].Operation.Path)
+	})
+
+	t.Run("reports position of invalid controller", func(t *testing.T) {
+		file := createTempFile(t, `package api
+
+// @RestController( path = 1 )
+type Users struct{}
+`)
+		_, err := ParseRouteMetadata(file)
+		var annErr *AnnotationError
+		require.ErrorAs(t, err, &annErr)
+		assert.Equal(t, 3, annErr.Pos.Line)
+	})
+}
+
+func TestJoinPath(t *testing.T) {
+	tests := []struct {
+		prefix, path, want string
+	}{
+		{"/person", "", "/person"},
+		{"", "/person", "/person"},
+		{"/person", "{id}", "/person/{id}"},
+		{"/person/", "/{id}", "/person/{id}"},
+		{"/person", "/", "/person"},
+		{"/person/", "/", "/person"},
+		{"/", "/", "/"},
+		{"/", "/health", "/health"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.prefix+" "+tt.path, func(t *testing.T) {
+			assert.Equal(t, tt.want, joinPath(tt.prefix, tt.path))
+		})
+	}
+}
+
+func TestRestControllerAccessLog(t *testing.T) {
+	file := createTempFile(t, `package api
+
+// @RestController( path = "/health", disableAccessLog = true )
+type Health struct{}
+
+// @RestOperation( method = "GET" )
+func (h *Health) Live() {}
+
+// @RestOperation( method = "GET", path = "/deep", disableAccessLog = false )
+func (h *Health) Deep() {}
+`)
+	routes, err := ParseRouteMetadata(file)
+	require.NoError(t, err)
+	require.Len(t, routes, 2)
+	assert.True(t, routes[0].Operation.DisableAccessLog)
+	assert.False(t, routes[1].Operation.DisableAccessLog)
+}
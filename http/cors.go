@@ -0,0 +1,356 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CORS is the cross-origin policy declared with cors = { origins = [...], headers = [...], credentials = true }
+// on an operation or a controller. Origins may be "*" unless credentials are allowed.
+type CORS struct {
+	Origins       []string `json:"origins"`
+	Headers       []string `json:"headers,omitempty"`
+	ExposeHeaders []string `json:"exposeHeaders,omitempty"`
+	Credentials   bool     `json:"credentials,omitempty"`
+	MaxAge        int      `json:"maxAge,omitempty"`
+}
+
+var ErrInvalidCORS = errors.New("invalid cors")
+
+// parseCORS reads a cors object, see CORS.
+func parseCORS(v *annotationValue) (*CORS, error) {
+	if v.kind != objectValue {
+		return nil, fmt.Errorf("expected object, found %s", v.kind)
+	}
+	c := &CORS{}
+	for _, field := range v.fields {
+		var err error
+		switch field.key {
+		case "origins":
+			c.Origins, err = field.value.asStrings()
+		case "headers":
+			c.Headers, err = field.value.asStrings()
+		case "exposeHeaders":
+			c.ExposeHeaders, err = field.value.asStrings()
+		case "credentials":
+			c.Credentials, err = field.value.asBool()
+		case "maxAge":
+			c.MaxAge, err = parseTimeout(field.value)
+		default:
+			return nil, fmt.Errorf("%w: unknown field %s", ErrInvalidCORS, field.key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", field.key, err)
+		}
+	}
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// validate checks the policy, whether parsed from an annotation or decoded from a manifest.
+func (c *CORS) validate() error {
+	if len(c.Origins) == 0 {
+		return fmt.Errorf("%w: origins are required", ErrInvalidCORS)
+	}
+	if c.Credentials && containsString(c.Origins, "*") {
+		return fmt.Errorf("%w: origin * cannot be used with credentials", ErrInvalidCORS)
+	}
+	if c.MaxAge < 0 {
+		return fmt.Errorf("%w: maxAge must not be negative", ErrInvalidCORS)
+	}
+	return nil
+}
+
+// allowOrigin returns the Access-Control-Allow-Origin value for origin, or "" when it is not allowed.
+func (c *CORS) allowOrigin(origin string) string {
+	if origin == "" {
+		return ""
+	}
+	if containsString(c.Origins, "*") {
+		return "*"
+	}
+	for _, allowed := range c.Origins {
+		if strings.EqualFold(allowed, origin) {
+			return origin
+		}
+	}
+	return ""
+}
+
+// allowHeaders reports whether every header of a comma-separated Access-Control-Request-Headers is allowed.
+func (c *CORS) allowHeaders(requested string) bool {
+	if containsString(c.Headers, "*") {
+		return true
+	}
+	for _, header := range strings.Split(requested, ",") {
+		header = strings.TrimSpace(header)
+		if header == "" {
+			continue
+		}
+		allowed := false
+		for _, h := range c.Headers {
+			if strings.EqualFold(h, header) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+	return true
+}
+
+// WithCORS wraps handler so responses to allowed origins carry the CORS headers of route.
+// Routes without cors are returned as is.
+func WithCORS(handler http.HandlerFunc, route *RouteMetadata) http.HandlerFunc {
+	c := route.Operation.CORS
+	if c == nil {
+		return handler
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		h.Add("Vary", "Origin")
+		if origin := c.allowOrigin(r.Header.Get("Origin")); origin != "" {
+			h.Set("Access-Control-Allow-Origin", origin)
+			if c.Credentials {
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+			if len(c.ExposeHeaders) > 0 {
+				h.Set("Access-Control-Expose-Headers", strings.Join(c.ExposeHeaders, ", "))
+			}
+		}
+		handler(w, r)
+	}
+}
+
+// copyHeader copies the header src buffered by a handler into dst, the header of the response.
+// Vary values are merged, so the Vary: Origin set by WithCORS around the buffering is kept.
+func copyHeader(dst, src http.Header) {
+	for k, v := range src {
+		if k != "Vary" {
+			dst[k] = append([]string(nil), v...)
+			continue
+		}
+		for _, value := range v {
+			if !containsString(dst[k], value) {
+				dst[k] = append(dst[k], value)
+			}
+		}
+	}
+}
+
+// preflight answers the OPTIONS requests of one path, for every method registered on it with cors,
+// unless an annotated OPTIONS operation registered later took it over.
+type preflight struct {
+	mu      sync.RWMutex
+	methods map[string]*CORS
+	handler http.HandlerFunc
+	owner   string
+}
+
+// preflightPrefix starts the route names of preflight routes.
+const preflightPrefix = "preflight "
+
+// preflightKey identifies the preflight route of a path and request matchers on a target.
+type preflightKey struct {
+	pathKey
+	match string
+}
+
+// preflightMatch returns the matchers of the preflight route of op. Browsers send preflights
+// to the URL of the request but without its headers, so header matchers are left out.
+func preflightMatch(op *RestOperation) RouteMatch {
+	match := op.Match()
+	match.Headers = nil
+	return match
+}
+
+func (p *preflight) add(method string, c *CORS) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.methods[method] = c
+}
+
+// takeOver makes handler, of the annotated OPTIONS operation route, answer the requests of the preflight route.
+func (p *preflight) takeOver(route *RouteMetadata, handler http.HandlerFunc) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.handler, p.owner = handler, route.RouteName()
+}
+
+// explicitPreflight returns the automatic preflight route that route, an annotated OPTIONS
+// operation, takes over because it has the same path and matchers on the target of key. Routers
+// cannot remove the preflight route, so the operation is served through it instead.
+func (reg *Registry) explicitPreflight(key any, route *RouteMetadata) (*preflight, error) {
+	op := route.Operation
+	if op.Method != http.MethodOptions {
+		return nil, nil
+	}
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	p := reg.preflights[preflightKey{pathKey{key, normalizePath(routePattern(op.Path))}, op.Match().key()}]
+	if p == nil {
+		return nil, nil
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.handler != nil {
+		return nil, fmt.Errorf("duplicate route %s %s, already registered by %s", op.Method, op.Path, p.owner)
+	}
+	return p, nil
+}
+
+// withoutPreflights returns existing without the automatic preflight routes route conflicts with.
+func withoutPreflights(existing []registeredRoute, route *RouteMetadata) []registeredRoute {
+	normalized, match := normalizePath(routePattern(route.Operation.Path)), route.Operation.Match().key()
+	var routes []registeredRoute
+	for _, other := range existing {
+		if strings.HasPrefix(other.name, preflightPrefix) && other.method == http.MethodOptions && normalizePath(other.path) == normalized && other.match == match {
+			continue
+		}
+		routes = append(routes, other)
+	}
+	return routes
+}
+
+// ServeHTTP answers a preflight request with 204 No Content, allowing the methods of the path
+// whose policy allows the origin, or with 403 Forbidden when the requested method, origin or
+// headers are not allowed. Preflight requests are not authenticated.
+func (p *preflight) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mu.RLock()
+	handler := p.handler
+	p.mu.RUnlock()
+	if handler != nil {
+		handler(w, r)
+		return
+	}
+	w.Header().Add("Vary", "Origin, Access-Control-Request-Method, Access-Control-Request-Headers")
+	origin := r.Header.Get("Origin")
+	requested := r.Header.Get("Access-Control-Request-Headers")
+	p.mu.RLock()
+	c := p.methods[r.Header.Get("Access-Control-Request-Method")]
+	var methods []string
+	for method, mc := range p.methods {
+		if mc.allowOrigin(origin) != "" {
+			methods = append(methods, method)
+		}
+	}
+	p.mu.RUnlock()
+	if c == nil || c.allowOrigin(origin) == "" || !c.allowHeaders(requested) {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+	sort.Strings(methods)
+	h := w.Header()
+	h.Set("Access-Control-Allow-Origin", c.allowOrigin(origin))
+	h.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+	if requested != "" {
+		h.Set("Access-Control-Allow-Headers", requested)
+	}
+	if c.Credentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if c.MaxAge > 0 {
+		h.Set("Access-Control-Max-Age", strconv.Itoa(c.MaxAge))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// corsRoute is a route with cors and the key of its preflight route.
+type corsRoute struct {
+	route *RouteMetadata
+	key   preflightKey
+}
+
+// corsRoutes returns the routes with cors and the keys of their preflight routes on the target
+// of key, leaving out paths with an annotated OPTIONS route in existing.
+func corsRoutes(key any, existing []registeredRoute, routes []*RouteMetadata) []corsRoute {
+	explicit := make(map[preflightKey]bool)
+	for _, route := range existing {
+		if route.method == http.MethodOptions && !strings.HasPrefix(route.name, preflightPrefix) {
+			explicit[preflightKey{pathKey{key, normalizePath(route.path)}, route.match}] = true
+		}
+	}
+	var cors []corsRoute
+	for _, route := range routes {
+		op := route.Operation
+		pk := preflightKey{pathKey{key, normalizePath(routePattern(op.Path))}, preflightMatch(op).key()}
+		if op.CORS == nil || explicit[pk] || explicit[preflightKey{pk.pathKey, ""}] {
+			continue
+		}
+		cors = append(cors, corsRoute{route, pk})
+	}
+	return cors
+}
+
+// validatePreflights checks the preflight routes registerPreflights would add for routes with
+// validate, so a failing preflight route fails the registration before any route is added.
+func (reg *Registry) validatePreflights(key any, existing []registeredRoute, routes []*RouteMetadata, validate func(method, path string, match RouteMatch) error) []error {
+	if validate == nil {
+		return nil
+	}
+	var errs []error
+	added := make(map[preflightKey]bool)
+	for _, cr := range corsRoutes(key, existing, routes) {
+		reg.mu.RLock()
+		_, ok := reg.preflights[cr.key]
+		reg.mu.RUnlock()
+		if ok || added[cr.key] {
+			continue
+		}
+		added[cr.key] = true
+		op := cr.route.Operation
+		if err := validate(http.MethodOptions, routePattern(op.Path), preflightMatch(op)); err != nil {
+			errs = append(errs, &RouteError{Route: cr.route, Err: fmt.Errorf("invalid preflight: %w", err)})
+		}
+	}
+	return errs
+}
+
+// registerPreflights adds the cors routes to the preflight of their path and matchers on target,
+// registering an OPTIONS route with those matchers for the ones that have none yet. Paths with an
+// annotated OPTIONS route are left to it. Preflights are shared by later registrations on the same target.
+func (reg *Registry) registerPreflights(target RouteTarget, key any, existing []registeredRoute, routes []*RouteMetadata) []error {
+	var errs []error
+	for _, cr := range corsRoutes(key, existing, routes) {
+		route, pk := cr.route, cr.key
+		op := route.Operation
+		path := routePattern(op.Path)
+		match := preflightMatch(op)
+		reg.mu.Lock()
+		if reg.preflights == nil {
+			reg.preflights = make(map[preflightKey]*preflight)
+		}
+		p, ok := reg.preflights[pk]
+		if !ok {
+			p = &preflight{methods: make(map[string]*CORS)}
+			reg.preflights[pk] = p
+		}
+		reg.mu.Unlock()
+		p.add(op.Method, op.CORS)
+		if ok {
+			continue
+		}
+		name := preflightPrefix + path
+		preflightOp := &RestOperation{Method: http.MethodOptions, Path: op.Path, Queries: match.Queries, Host: match.Host, Schemes: match.Schemes, DisableAuth: true}
+		if err := handleRoute(target, preflightOp, path, name, p.ServeHTTP); err != nil {
+			reg.mu.Lock()
+			delete(reg.preflights, pk)
+			reg.mu.Unlock()
+			errs = append(errs, &RouteError{Route: route, Err: fmt.Errorf("failed to register preflight: %w", err)})
+			continue
+		}
+		reg.mu.Lock()
+		reg.routes = append(reg.routes, RouteInfo{Name: name, Method: http.MethodOptions, Path: op.Path, Handler: "rest.preflight", DisableAuth: true, Queries: match.Queries, Host: match.Host, Schemes: match.Schemes})
+		reg.mu.Unlock()
+	}
+	return errs
+}
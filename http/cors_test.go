@@ -0,0 +1,243 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type corsHandler struct{}
+
+// @RestOperation( method = "GET", path = "/items/{id}", cors = { origins = ["https://app.example"], exposeHeaders = ["ETag"], credentials = true } )
+func (h *corsHandler) GetItem(w http.ResponseWriter, r *http.Request) {}
+
+// @RestOperation( method = "PUT", path = "/items/{id}", cors = { origins = ["https://app.example"], headers = ["Content-Type", "Authorization"], credentials = true, maxAge = 10m } )
+func (h *corsHandler) PutItem(w http.ResponseWriter, r *http.Request) {}
+
+// @RestOperation( method = "DELETE", path = "/items/{id}" )
+func (h *corsHandler) DeleteItem(w http.ResponseWriter, r *http.Request) {}
+
+type publicCORSHandler struct{}
+
+// @RestOperation( method = "POST", path = "/items/{itemID}", disableAuth = true, cors = { origins = ["*"] } )
+func (h *publicCORSHandler) PostItem(w http.ResponseWriter, r *http.Request) {}
+
+func TestParseCORS(t *testing.T) {
+	t.Run("parses cors object", func(t *testing.T) {
+		op, err := ParseRestOperation(`@RestOperation( method = "GET", path = "/", cors = { origins = ["https://a.example"], headers = ["X-Token"], credentials = true, maxAge = 1m } )`)
+		require.NoError(t, err)
+		assert.Equal(t, &CORS{Origins: []string{"https://a.example"}, Headers: []string{"X-Token"}, Credentials: true, MaxAge: 60}, op.CORS)
+	})
+
+	t.Run("rejects invalid policies", func(t *testing.T) {
+		_, err := ParseRestOperation(`@RestOperation( method = "GET", path = "/", cors = { headers = ["X-Token"] } )`)
+		assert.ErrorContains(t, err, "invalid cors value: invalid cors: origins are required")
+		_, err = ParseRestOperation(`@RestOperation( method = "GET", path = "/", cors = { origins = ["*"], credentials = true } )`)
+		assert.ErrorIs(t, err, ErrInvalidCORS)
+		_, err = ParseRestOperation(`@RestOperation( method = "GET", path = "/", cors = { origins = ["*"], methods = ["GET"] } )`)
+		assert.ErrorContains(t, err, "unknown field methods")
+		_, err = ParseRestOperation(`@RestOperation( method = "GET", path = "/", cors = ["*"] )`)
+		assert.ErrorContains(t, err, "invalid cors value: expected object, found array")
+	})
+
+	t.Run("accepts OPTIONS operations", func(t *testing.T) {
+		_, err := ParseRestOperation(`@RestOperation( method = "OPTIONS", path = "/" )`)
+		assert.NoError(t, err)
+	})
+
+	t.Run("applies controller policy", func(t *testing.T) {
+		file := createTempFile(t, `package api
+
+// @RestController( path = "/api", cors = { origins = ["https://app.example"] } )
+type API struct{}
+
+// @RestOperation( method = "GET", path = "/a" )
+func (a *API) A() {}
+
+// @RestOperation( method = "GET", path = "/b", cors = { origins = ["*"] } )
+func (a *API) B() {}
+`)
+		routes, err := ParseRouteMetadata(file)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"https://app.example"}, routes[0].Operation.CORS.Origins)
+		assert.Equal(t, []string{"*"}, routes[1].Operation.CORS.Origins)
+	})
+}
+
+func TestCORS(t *testing.T) {
+	registry := NewRegistry()
+	require.NoError(t, registry.RegisterAuthenticator(tokenAuthenticator{}))
+	router := mux.NewRouter()
+	require.NoError(t, registry.RegisterRoutes(router, &corsHandler{}, "./cors_test.go"))
+	require.NoError(t, registry.RegisterRoutes(router, &publicCORSHandler{}, "./cors_test.go"))
+	preflight := func(origin, method, headers string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodOptions, "/items/7", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", method)
+		if headers != "" {
+			req.Header.Set("Access-Control-Request-Headers", headers)
+		}
+		return serveRequest(router, req)
+	}
+
+	t.Run("answers preflight without authentication", func(t *testing.T) {
+		res := preflight("https://app.example", "PUT", "content-type")
+		assert.Equal(t, http.StatusNoContent, res.Code)
+		assert.Equal(t, "https://app.example", res.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "GET, POST, PUT", res.Header().Get("Access-Control-Allow-Methods"))
+		assert.Equal(t, "content-type", res.Header().Get("Access-Control-Allow-Headers"))
+		assert.Equal(t, "true", res.Header().Get("Access-Control-Allow-Credentials"))
+		assert.Equal(t, "600", res.Header().Get("Access-Control-Max-Age"))
+	})
+
+	t.Run("aggregates methods allowing the origin", func(t *testing.T) {
+		res := preflight("https://other.example", "POST", "")
+		assert.Equal(t, http.StatusNoContent, res.Code)
+		assert.Equal(t, "*", res.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "POST", res.Header().Get("Access-Control-Allow-Methods"))
+	})
+
+	t.Run("rejects disallowed preflights", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, preflight("https://other.example", "PUT", "").Code)
+		assert.Equal(t, http.StatusForbidden, preflight("https://app.example", "DELETE", "").Code)
+		assert.Equal(t, http.StatusForbidden, preflight("https://app.example", "PUT", "X-Debug").Code)
+	})
+
+	t.Run("adds headers to actual responses", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/items/7", nil)
+		req.Header.Set("Origin", "https://app.example")
+		res := serveRequest(router, req)
+		assert.Equal(t, http.StatusUnauthorized, res.Code)
+		assert.Equal(t, "https://app.example", res.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "ETag", res.Header().Get("Access-Control-Expose-Headers"))
+		assert.Equal(t, "Origin", res.Header().Get("Vary"))
+
+		req.Header.Set("Origin", "https://other.example")
+		assert.Empty(t, serveRequest(router, req).Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("lists preflight routes", func(t *testing.T) {
+		var preflights []string
+		for _, route := range registry.Routes() {
+			if route.Method == http.MethodOptions {
+				preflights = append(preflights, route.Path)
+			}
+		}
+		assert.Equal(t, []string{"/items/{id}"}, preflights)
+	})
+
+	t.Run("registers preflights with the route matchers", func(t *testing.T) {
+		tenant := func(host, origin string) BoundRoute {
+			return BoundRoute{Route: &RouteMetadata{
+				Operation:     &RestOperation{Method: "GET", Path: "/settings", Host: host, DisableAuth: true, CORS: &CORS{Origins: []string{origin}}},
+				Package:       "http",
+				HandlerType:   "tenantHandler",
+				HandlerMethod: strings.Split(host, ".")[0],
+			}, Handler: func(w http.ResponseWriter, r *http.Request) {}}
+		}
+		router := mux.NewRouter()
+		require.NoError(t, NewRegistry().RegisterBoundRoutesTo(MuxTarget(router),
+			tenant("a.example", "https://a.app"),
+			tenant("b.example", "https://b.app"),
+		))
+		preflight := func(host, origin string) int {
+			req := httptest.NewRequest(http.MethodOptions, "http://"+host+"/settings", nil)
+			req.Header.Set("Origin", origin)
+			req.Header.Set("Access-Control-Request-Method", "GET")
+			return serveRequest(router, req).Code
+		}
+		assert.Equal(t, http.StatusNoContent, preflight("a.example", "https://a.app"))
+		assert.Equal(t, http.StatusNoContent, preflight("b.example", "https://b.app"))
+		assert.Equal(t, http.StatusForbidden, preflight("a.example", "https://b.app"))
+		assert.Equal(t, http.StatusNotFound, preflight("c.example", "https://a.app"))
+	})
+
+	t.Run("leaves annotated OPTIONS routes alone", func(t *testing.T) {
+		file := createTempFile(t, `package http
+
+// @RestOperation( method = "GET", path = "/person/{uid}", disableAuth = true, cors = { origins = ["*"] } )
+func (h *personRoutes) Get(w http.ResponseWriter, r *http.Request) {}
+
+// @RestOperation( method = "OPTIONS", path = "/person/{uid}", disableAuth = true )
+func (h *personRoutes) Me(w http.ResponseWriter, r *http.Request) {}
+`)
+		serveMux := http.NewServeMux()
+		require.NoError(t, NewRegistry().RegisterRoutesTo(ServeMuxTarget(serveMux), &personRoutes{}, file))
+		req := httptest.NewRequest(http.MethodOptions, "/person/bill", nil)
+		assert.Equal(t, "me", serveRequest(serveMux, req).Body.String())
+	})
+
+	t.Run("lets a later OPTIONS operation take over the preflight", func(t *testing.T) {
+		route := func(method string, cors *CORS) BoundRoute {
+			return BoundRoute{Route: &RouteMetadata{
+				Operation:     &RestOperation{Method: method, Path: "/person/{uid}", DisableAuth: true, CORS: cors},
+				Package:       "http",
+				HandlerType:   "personRoutes",
+				HandlerMethod: method,
+			}, Handler: func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(method + " " + PathParam(r, "uid"))) }}
+		}
+		for name, target := range map[string]func() (RouteTarget, http.Handler){
+			"mux": func() (RouteTarget, http.Handler) { router := mux.NewRouter(); return MuxTarget(router), router },
+			"ServeMux": func() (RouteTarget, http.Handler) {
+				serveMux := http.NewServeMux()
+				return ServeMuxTarget(serveMux), serveMux
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				registry := NewRegistry()
+				target, handler := target()
+				require.NoError(t, registry.RegisterBoundRoutesTo(target, route("GET", &CORS{Origins: []string{"*"}})))
+				preflight := httptest.NewRequest(http.MethodOptions, "/person/bill", nil)
+				preflight.Header.Set("Origin", "https://app.example")
+				preflight.Header.Set("Access-Control-Request-Method", "GET")
+				assert.Equal(t, http.StatusNoContent, serveRequest(handler, preflight).Code)
+
+				require.NoError(t, registry.RegisterBoundRoutesTo(target, route("OPTIONS", nil)))
+				assert.Equal(t, "OPTIONS bill", serveRequest(handler, preflight).Body.String())
+				assert.Equal(t, "http.personRoutes.OPTIONS", registry.Routes()[len(registry.Routes())-1].Name)
+				err := registry.RegisterBoundRoutesTo(target, route("OPTIONS", nil))
+				assert.ErrorContains(t, err, "duplicate route OPTIONS /person/{uid}")
+			})
+		}
+	})
+
+	bound := func(method, path string, op RestOperation, handler http.HandlerFunc) BoundRoute {
+		op.Method, op.Path, op.DisableAuth = method, path, true
+		return BoundRoute{Route: &RouteMetadata{Operation: &op, Package: "http", HandlerType: "corsHandler", HandlerMethod: method + strings.ReplaceAll(path, "/", "_")}, Handler: handler}
+	}
+
+	t.Run("keeps Vary: Origin of buffered responses", func(t *testing.T) {
+		varyHandler := func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Vary", "Accept-Encoding")
+			w.Write([]byte("ok"))
+		}
+		cors := &CORS{Origins: []string{"*"}}
+		router := mux.NewRouter()
+		require.NoError(t, NewRegistry().RegisterBoundRoutesTo(MuxTarget(router),
+			bound("GET", "/timed", RestOperation{Timeout: 5, CORS: cors}, varyHandler),
+			bound("GET", "/cached", RestOperation{Cache: "no-cache", ETag: true, CORS: cors}, varyHandler),
+		))
+		for _, path := range []string{"/timed", "/cached"} {
+			req := httptest.NewRequest("GET", path, nil)
+			req.Header.Set("Origin", "https://app.example")
+			res := serveRequest(router, req)
+			assert.Equal(t, []string{"Origin", "Accept-Encoding"}, res.Header().Values("Vary"), path)
+		}
+	})
+
+	t.Run("registers no route when a preflight route fails", func(t *testing.T) {
+		serveMux := http.NewServeMux()
+		noop := func(w http.ResponseWriter, r *http.Request) {}
+		err := NewRegistry().RegisterBoundRoutesTo(ServeMuxTarget(serveMux),
+			bound("OPTIONS", "/{kind}/new", RestOperation{}, noop),
+			bound("GET", "/items/{id}", RestOperation{CORS: &CORS{Origins: []string{"*"}}}, noop),
+		)
+		assert.ErrorContains(t, err, "invalid preflight")
+		assert.Equal(t, http.StatusNotFound, serve(serveMux, "/items/1").Code)
+	})
+}
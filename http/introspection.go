@@ -0,0 +1,98 @@
+package http
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"strings"
+)
+
+// RouteInfo describes a route registered through a Registry. Authenticated reports whether the
+// authenticator guards the route, which routes without disableAuth lack when RequireAuthenticator(false)
+// let them register without one.
+type RouteInfo struct {
+	Name          string   `json:"name"`
+	Method        string   `json:"method"`
+	Path          string   `json:"path"`
+	Handler       string   `json:"handler"`
+	Middlewares   []string `json:"middlewares"`
+	Timeout       int      `json:"timeout"`
+	DisableAuth   bool     `json:"disableAuth"`
+	Authenticated bool     `json:"authenticated"`
+	Order         int      `json:"order,omitempty"`
+	Queries       []string `json:"queries,omitempty"`
+	Headers       []string `json:"headers,omitempty"`
+	Host          string   `json:"host,omitempty"`
+	Schemes       []string `json:"schemes,omitempty"`
+	RateLimit     string   `json:"rateLimit,omitempty"`
+	CORS          *CORS    `json:"cors,omitempty"`
+	SourceFile    string   `json:"sourceFile,omitempty"`
+}
+
+func newRouteInfo(route *RouteMetadata, authenticated bool) RouteInfo {
+	return RouteInfo{
+		Name:          route.RouteName(),
+		Method:        route.Operation.Method,
+		Path:          route.Operation.Path,
+		Handler:       route.HandlerName(),
+		Middlewares:   append([]string{}, route.Operation.Middlewares...),
+		Timeout:       route.Operation.Timeout,
+		DisableAuth:   route.Operation.DisableAuth,
+		Authenticated: authenticated,
+		Order:         route.Operation.Order,
+		Queries:       route.Operation.Queries,
+		Headers:       route.Operation.Headers,
+		Host:          route.Operation.Host,
+		Schemes:       route.Operation.Schemes,
+		RateLimit:     route.Operation.RateLimit,
+		CORS:          route.Operation.CORS,
+		SourceFile:    route.SourceFile,
+	}
+}
+
+// Routes returns every route registered through the registry, in registration order.
+func (reg *Registry) Routes() []RouteInfo {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	return append([]RouteInfo{}, reg.routes...)
+}
+
+// RoutesHandler serves the route table as JSON, or as an HTML table when the client
+// accepts text/html or the request has ?format=html.
+func (reg *Registry) RoutesHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		routes := reg.Routes()
+		format := r.URL.Query().Get("format")
+		if format == "html" || format == "" && strings.Contains(r.Header.Get("Accept"), "text/html") {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			routesTemplate.Execute(w, routes)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(routes)
+	})
+}
+
+// Routes returns the routes registered through the default registry.
+func Routes() []RouteInfo {
+	return defaultRegistry.Routes()
+}
+
+// RoutesHandler serves the route table of the default registry.
+func RoutesHandler() http.Handler {
+	return defaultRegistry.RoutesHandler()
+}
+
+var routesTemplate = template.Must(template.New("routes").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Routes</title>
+<style>table{border-collapse:collapse;font-family:sans-serif}th,td{border:1px solid #ccc;padding:4px 8px;text-align:left}</style>
+</head>
+<body>
+<table>
+<tr><th>Name</th><th>Method</th><th>Path</th><th>Handler</th><th>Middlewares</th><th>Timeout</th><th>Auth</th><th>Rate limit</th></tr>
+{{range .}}<tr><td>{{.Name}}</td><td>{{.Method}}</td><td>{{.Path}}</td><td>{{.Handler}}</td><td>{{range $i, $m := .Middlewares}}{{if $i}}, {{end}}{{$m}}{{end}}</td><td>{{.Timeout}}s</td><td>{{if .Authenticated}}required{{else if .DisableAuth}}disabled{{else}}none{{end}}</td><td>{{.RateLimit}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
@@ -0,0 +1,115 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoutes(t *testing.T) {
+	registry := NewRegistry()
+	require.NoError(t, registry.RegisterMiddleware("TestMiddleWare", testMiddleWare))
+	router := mux.NewRouter()
+	require.NoError(t, registry.RegisterRoutes(router, &Handler{}, "./route_register_test.go"))
+
+	t.Run("returns registered routes in order", func(t *testing.T) {
+		routes := registry.Routes()
+		require.Len(t, routes, 2)
+		assert.Equal(t, RouteInfo{
+			Name:        "http.Handler.Get",
+			Method:      "GET",
+			Path:        "/person/{uid}",
+			Handler:     "http.(*Handler).Get",
+			Middlewares: []string{"TestMiddleWare"},
+			Timeout:     30,
+			DisableAuth: true,
+			SourceFile:  "./route_register_test.go",
+		}, routes[0])
+		assert.Equal(t, "http.Handler.Post", routes[1].Name)
+		assert.Empty(t, NewRegistry().Routes())
+	})
+
+	t.Run("serves route table as JSON", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		registry.RoutesHandler().ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/debug/routes", nil))
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, "application/json", res.Header().Get("Content-Type"))
+		var routes []RouteInfo
+		require.NoError(t, json.Unmarshal(res.Body.Bytes(), &routes))
+		assert.Equal(t, registry.Routes(), routes)
+	})
+
+	t.Run("serves route table as HTML", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/debug/routes", nil)
+		req.Header.Set("Accept", "text/html,application/xhtml+xml")
+		res := httptest.NewRecorder()
+		registry.RoutesHandler().ServeHTTP(res, req)
+		assert.Equal(t, "text/html; charset=utf-8", res.Header().Get("Content-Type"))
+		assert.Contains(t, res.Body.String(), "<td>/person/{uid}</td>")
+		assert.Contains(t, res.Body.String(), "<td>TestMiddleWare</td>")
+
+		res = httptest.NewRecorder()
+		registry.RoutesHandler().ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/debug/routes?format=html", nil))
+		assert.Contains(t, res.Body.String(), "<table>")
+	})
+}
+
+func TestRoutesAuthentication(t *testing.T) {
+	authState := func(registry *Registry) map[string]string {
+		router := mux.NewRouter()
+		require.NoError(t, registry.RegisterRoutes(router, &securedHandler{}, "./auth_test.go"))
+		res := httptest.NewRecorder()
+		registry.RoutesHandler().ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/debug/routes?format=html", nil))
+		states := make(map[string]string)
+		for _, route := range registry.Routes() {
+			states[route.Path] = fmt.Sprint(route.DisableAuth, route.Authenticated)
+		}
+		states["html"] = res.Body.String()
+		return states
+	}
+
+	t.Run("reports routes guarded by the authenticator", func(t *testing.T) {
+		registry := NewRegistry()
+		require.NoError(t, registry.RegisterAuthenticator(tokenAuthenticator{}))
+		states := authState(registry)
+		assert.Equal(t, "false true", states["/secure"])
+		assert.Equal(t, "true false", states["/public"])
+		assert.Contains(t, states["html"], "<td>required</td>")
+		assert.Contains(t, states["html"], "<td>disabled</td>")
+	})
+
+	t.Run("reports routes served without an authenticator", func(t *testing.T) {
+		registry := NewRegistry()
+		registry.RequireAuthenticator(false)
+		states := authState(registry)
+		assert.Equal(t, "false false", states["/secure"])
+		assert.Contains(t, states["html"], "<td>none</td>")
+		assert.NotContains(t, states["html"], "<td>required</td>")
+	})
+}
+
+func TestHandlerName(t *testing.T) {
+	file := createTempFile(t, `package api
+
+// @RestOperation( method = "GET", path = "/a" )
+func (s *Service) Pointer(w http.ResponseWriter, r *http.Request) {}
+
+// @RestOperation( method = "GET", path = "/b" )
+func (s Service) Value(w http.ResponseWriter, r *http.Request) {}
+
+// @RestOperation( method = "GET", path = "/c" )
+func Health(w http.ResponseWriter, r *http.Request) {}
+`)
+	routes, err := ParseRouteMetadata(file)
+	require.NoError(t, err)
+	require.Len(t, routes, 3)
+	assert.Equal(t, "api.(*Service).Pointer", routes[0].HandlerName())
+	assert.Equal(t, "api.Service.Value", routes[1].HandlerName())
+	assert.Equal(t, "api.Health", routes[2].HandlerName())
+}
@@ -0,0 +1,104 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/gorilla/mux"
+)
+
+// ManifestVersion is the schema version of manifests written by this package. It changes with
+// every field added to RestOperation or RouteMetadata, older manifests must be regenerated.
+//
+// Version 2 added the access log, metrics, rate limit, CORS, cache and etag options of
+// operations and the value receiver of routes.
+const ManifestVersion = 2
+
+// ErrUnsupportedManifest is returned when loading a manifest of another schema version.
+var ErrUnsupportedManifest = errors.New("unsupported manifest version")
+
+// Manifest is a serialized list of parsed routes, with their source positions, so routes
+// can be registered without parsing Go sources at startup and consumed by other tools.
+type Manifest struct {
+	Version int              `json:"version"`
+	Routes  []*RouteMetadata `json:"routes"`
+}
+
+// NewManifest returns a manifest of routes using the current schema version.
+func NewManifest(routes []*RouteMetadata) *Manifest {
+	if routes == nil {
+		routes = []*RouteMetadata{}
+	}
+	return &Manifest{Version: ManifestVersion, Routes: routes}
+}
+
+// JSON encodes the manifest as indented JSON.
+func (m *Manifest) JSON() ([]byte, error) {
+	return json.MarshalIndent(m, "", "  ")
+}
+
+// ParseManifest decodes a JSON manifest and validates its routes. Unknown fields are rejected,
+// so options of a newer schema are never silently dropped.
+func ParseManifest(data []byte) (*Manifest, error) {
+	var header struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	if header.Version != ManifestVersion {
+		return nil, fmt.Errorf("%w %d, expected %d", ErrUnsupportedManifest, header.Version, ManifestVersion)
+	}
+	var m Manifest
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	var errs []error
+	for _, route := range m.Routes {
+		if route == nil || route.Operation == nil {
+			return nil, errors.New("failed to parse manifest: route without operation")
+		}
+		if err := route.Operation.Validate(); err != nil {
+			errs = append(errs, &RouteError{Route: route, Err: err})
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	return &m, nil
+}
+
+// LoadManifest reads and parses the manifest file name of fsys, e.g. an embed.FS.
+func LoadManifest(fsys fs.FS, name string) (*Manifest, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	return ParseManifest(data)
+}
+
+// RegisterRoutesFromManifest registers the routes of manifest implemented by handler on the
+// router, without parsing Go sources.
+func (reg *Registry) RegisterRoutesFromManifest(router *mux.Router, handler any, manifest *Manifest) error {
+	return reg.RegisterRoutesFromManifestTo(MuxTarget(router), handler, manifest)
+}
+
+// RegisterRoutesFromManifestTo is RegisterRoutesFromManifest for any RouteTarget.
+func (reg *Registry) RegisterRoutesFromManifestTo(target RouteTarget, handler any, manifest *Manifest) error {
+	return reg.registerHandlerRoutes(target, handler, manifest.Routes)
+}
+
+// RegisterRoutesFromManifest registers the routes of manifest using the default registry.
+func RegisterRoutesFromManifest(router *mux.Router, handler any, manifest *Manifest) error {
+	return defaultRegistry.RegisterRoutesFromManifest(router, handler, manifest)
+}
+
+// RegisterRoutesFromManifestTo registers the routes of manifest on target using the default registry.
+func RegisterRoutesFromManifestTo(target RouteTarget, handler any, manifest *Manifest) error {
+	return defaultRegistry.RegisterRoutesFromManifestTo(target, handler, manifest)
+}
@@ -0,0 +1,106 @@
+package http
+
+import (
+	"net/http"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManifest(t *testing.T) {
+	routes, err := ParseRouteMetadata("./route_target_test.go")
+	require.NoError(t, err)
+	data, err := NewManifest(routes).JSON()
+	require.NoError(t, err)
+
+	t.Run("round trips routes with positions", func(t *testing.T) {
+		manifest, err := ParseManifest(data)
+		require.NoError(t, err)
+		assert.Equal(t, ManifestVersion, manifest.Version)
+		assert.Equal(t, routes, manifest.Routes)
+		assert.Equal(t, "./route_target_test.go:15", manifest.Routes[0].Position())
+	})
+
+	t.Run("registers routes without parsing sources", func(t *testing.T) {
+		manifest, err := LoadManifest(fstest.MapFS{"routes.json": {Data: data}}, "routes.json")
+		require.NoError(t, err)
+		router := mux.NewRouter()
+		require.NoError(t, NewRegistry().RegisterRoutesFromManifest(router, &targetHandler{}, manifest))
+		assert.Equal(t, "item 42", serve(router, "/items/42").Body.String())
+		assert.Equal(t, http.StatusNotFound, serve(router, "/items/abc").Code)
+	})
+
+	t.Run("round trips every operation option", func(t *testing.T) {
+		route := &RouteMetadata{
+			Operation: &RestOperation{
+				Method:           "GET",
+				Path:             "/docs/{id}",
+				Middlewares:      []string{"RequireRole(\"admin\")"},
+				Timeout:          10,
+				Order:            -1,
+				Queries:          []string{"q={q}"},
+				Headers:          []string{"X-API-Version=2"},
+				Host:             "{tenant}.example.com",
+				Schemes:          []string{"https"},
+				AccessLog:        true,
+				DisableAccessLog: true,
+				DisableMetrics:   true,
+				MetricsBuckets:   []float64{0.1, 1},
+				RateLimit:        "10/s burst 5 by ip",
+				CORS:             &CORS{Origins: []string{"https://app.example"}, Headers: []string{"Content-Type"}, ExposeHeaders: []string{"ETag"}, Credentials: true, MaxAge: 600},
+				Cache:            "private, max-age=60",
+				ETag:             true,
+			},
+			HandlerMethod: "Get",
+			HandlerType:   "Docs",
+			Package:       "api",
+			SourceFile:    "api.go",
+			Line:          7,
+			ImportPath:    "example.com/api",
+			Doc:           "Get returns a document.",
+			Signature:     "func(w http.ResponseWriter, r *http.Request)",
+			ValueReceiver: true,
+		}
+		data, err := NewManifest([]*RouteMetadata{route}).JSON()
+		require.NoError(t, err)
+		manifest, err := ParseManifest(data)
+		require.NoError(t, err)
+		assert.Equal(t, []*RouteMetadata{route}, manifest.Routes)
+	})
+
+	t.Run("rejects other versions", func(t *testing.T) {
+		_, err := ParseManifest([]byte(`{"version": 1, "routes": []}`))
+		assert.ErrorIs(t, err, ErrUnsupportedManifest)
+	})
+
+	t.Run("rejects unknown fields", func(t *testing.T) {
+		_, err := ParseManifest([]byte(`{"version": 2, "routes": [{"operation": {"method": "GET", "path": "/", "retries": 3}, "handlerMethod": "Get", "package": "api", "sourceFile": "api.go"}]}`))
+		assert.ErrorContains(t, err, `unknown field "retries"`)
+	})
+
+	t.Run("rejects invalid routes", func(t *testing.T) {
+		_, err := ParseManifest([]byte(`{"version": 2, "routes": [{"operation": {"method": "FETCH", "path": "/"}, "handlerMethod": "Get", "package": "api", "sourceFile": "api.go", "line": 3}]}`))
+		assert.ErrorIs(t, err, ErrInvalidMethod)
+		assert.ErrorContains(t, err, "api.go:3: api.Get: invalid HTTP method")
+	})
+
+	t.Run("rejects options that source parsing rejects", func(t *testing.T) {
+		for option, want := range map[string]string{
+			`"middlewares": ["Require Role"]`:                            "invalid middlewares value",
+			`"queries": ["=1"]`:                                          "invalid queries value",
+			`"headers": [" =2"]`:                                         "invalid headers value",
+			`"schemes": ["ftp"]`:                                         "invalid schemes value",
+			`"metricsBuckets": [1, 0.5]`:                                 "invalid metricsBuckets value",
+			`"rateLimit": "lots"`:                                        "invalid rateLimit value",
+			`"cors": {"origins": []}`:                                    "invalid cors value",
+			`"cors": {"origins": ["*"], "credentials": true}`:            "invalid cors value",
+			`"cors": {"origins": ["https://app.example"], "maxAge": -1}`: "invalid cors value",
+		} {
+			_, err := ParseManifest([]byte(`{"version": 2, "routes": [{"operation": {"method": "GET", "path": "/", ` + option + `}, "handlerMethod": "Get", "package": "api", "sourceFile": "api.go"}]}`))
+			assert.ErrorContains(t, err, want, option)
+		}
+	})
+}
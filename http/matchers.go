@@ -0,0 +1,95 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// RouteMatch holds the request matchers of an operation besides its method and path.
+// Queries and Headers are "key=value" pairs, the value may contain {variables} for queries
+// and may be omitted to only require the key. Host may contain {variables}, e.g.
+// "{tenant}.api.example.com".
+type RouteMatch struct {
+	Queries []string
+	Headers []string
+	Host    string
+	Schemes []string
+}
+
+// IsZero reports whether no matcher is set.
+func (m RouteMatch) IsZero() bool {
+	return len(m.Queries) == 0 && len(m.Headers) == 0 && m.Host == "" && len(m.Schemes) == 0
+}
+
+// key returns a canonical representation of the matchers, used to compare routes.
+func (m RouteMatch) key() string {
+	if m.IsZero() {
+		return ""
+	}
+	sorted := func(values []string) string {
+		values = append([]string{}, values...)
+		sort.Strings(values)
+		return strings.Join(values, "&")
+	}
+	return fmt.Sprintf("host=%s queries=%s headers=%s schemes=%s", m.Host, sorted(m.Queries), sorted(m.Headers), sorted(m.Schemes))
+}
+
+// matchPairs splits "key=value" matchers into the alternating key, value list used by gorilla mux.
+func matchPairs(values []string) []string {
+	pairs := make([]string, 0, 2*len(values))
+	for _, value := range values {
+		key, val, _ := strings.Cut(value, "=")
+		pairs = append(pairs, strings.TrimSpace(key), strings.TrimSpace(val))
+	}
+	return pairs
+}
+
+// Match returns the request matchers declared by the operation.
+func (r *RestOperation) Match() RouteMatch {
+	return RouteMatch{Queries: r.Queries, Headers: r.Headers, Host: r.Host, Schemes: r.Schemes}
+}
+
+// MatchingTarget is implemented by targets that can also match requests on query parameters,
+// headers, host and scheme. Routes using the queries, headers, host or schemes keys can only
+// be registered on such targets.
+type MatchingTarget interface {
+	RouteTarget
+	HandleMatching(method, path, name string, match RouteMatch, handler http.HandlerFunc) error
+}
+
+var errUnsupportedMatchers = errors.New("router does not support queries, headers, host or schemes matchers")
+
+// handleRoute registers handler on target, using its matchers when the operation declares any.
+func handleRoute(target RouteTarget, op *RestOperation, path, name string, handler http.HandlerFunc) error {
+	match := op.Match()
+	if match.IsZero() {
+		return target.Handle(op.Method, path, name, handler)
+	}
+	matching, ok := target.(MatchingTarget)
+	if !ok {
+		return errUnsupportedMatchers
+	}
+	return matching.HandleMatching(op.Method, path, name, match, handler)
+}
+
+// validateMatchPairs checks that every matcher is a "key=value" pair or a bare key.
+func validateMatchPairs(values []string) error {
+	for _, value := range values {
+		if key, _, _ := strings.Cut(value, "="); strings.TrimSpace(key) == "" {
+			return fmt.Errorf("%q must be key=value", value)
+		}
+	}
+	return nil
+}
+
+func validateSchemes(schemes []string) error {
+	for _, scheme := range schemes {
+		if scheme != "http" && scheme != "https" {
+			return fmt.Errorf("unsupported scheme %q", scheme)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type versionedHandler struct{}
+
+// @RestOperation( method = "GET", path = "/reports", headers = ["X-API-Version=2"], disableAuth = true )
+func (h *versionedHandler) ReportsV2(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("v2"))
+}
+
+// @RestOperation( method = "GET", path = "/reports", disableAuth = true )
+func (h *versionedHandler) Reports(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("v1"))
+}
+
+// @RestOperation( method = "GET", path = "/search", queries = ["q={q}", "page={page:[0-9]+}"], disableAuth = true )
+func (h *versionedHandler) Search(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprintf(w, "%s %s", PathParam(r, "q"), PathParam(r, "page"))
+}
+
+// @RestOperation( method = "GET", path = "/settings", host = "{tenant}.api.example.com", schemes = ["https"], disableAuth = true )
+func (h *versionedHandler) Settings(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte(PathParam(r, "tenant")))
+}
+
+func TestParseMatchers(t *testing.T) {
+	t.Run("parses matcher keys", func(t *testing.T) {
+		op, err := ParseRestOperation(`@RestOperation( method = "GET", path = "/", queries = ["page={page}"], headers = ["X-API-Version=2"], host = "{tenant}.example.com", schemes = ["https"] )`)
+		require.NoError(t, err)
+		assert.Equal(t, RouteMatch{Queries: []string{"page={page}"}, Headers: []string{"X-API-Version=2"}, Host: "{tenant}.example.com", Schemes: []string{"https"}}, op.Match())
+	})
+
+	t.Run("rejects invalid matchers", func(t *testing.T) {
+		_, err := ParseRestOperation(`@RestOperation( method = "GET", path = "/", schemes = ["ftp"] )`)
+		assert.ErrorContains(t, err, `invalid schemes value: unsupported scheme "ftp"`)
+		_, err = ParseRestOperation(`@RestOperation( method = "GET", path = "/", headers = ["=2"] )`)
+		assert.ErrorContains(t, err, `invalid headers value: "=2" must be key=value`)
+	})
+}
+
+func TestRouteMatchers(t *testing.T) {
+	router := mux.NewRouter()
+	registry := NewRegistry()
+	require.NoError(t, registry.RegisterRoutes(router, &versionedHandler{}, "./matchers_test.go"))
+
+	t.Run("routes by header", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/reports", nil)
+		req.Header.Set("X-API-Version", "2")
+		assert.Equal(t, "v2", serveRequest(router, req).Body.String())
+		assert.Equal(t, "v1", serve(router, "/reports").Body.String())
+	})
+
+	t.Run("routes by query", func(t *testing.T) {
+		assert.Equal(t, "go 2", serve(router, "/search?q=go&page=2").Body.String())
+		assert.Equal(t, http.StatusNotFound, serve(router, "/search?q=go&page=x").Code)
+	})
+
+	t.Run("routes by host and scheme", func(t *testing.T) {
+		assert.Equal(t, "acme", serve(router, "https://acme.api.example.com/settings").Body.String())
+		assert.Equal(t, http.StatusNotFound, serve(router, "https://example.com/settings").Code)
+		assert.Equal(t, http.StatusNotFound, serve(router, "http://acme.api.example.com/settings").Code)
+	})
+
+	t.Run("lists matchers in route table", func(t *testing.T) {
+		assert.Equal(t, []string{"X-API-Version=2"}, registry.Routes()[0].Headers)
+	})
+
+	t.Run("detects duplicates with the same matchers", func(t *testing.T) {
+		err := registry.RegisterRoutes(router, &versionedHandler{}, "./matchers_test.go")
+		assert.ErrorContains(t, err, "duplicate route GET /reports, already registered by http.versionedHandler.ReportsV2")
+	})
+
+	t.Run("reads matchers from the router, not from route names", func(t *testing.T) {
+		route := func(headers ...string) BoundRoute {
+			return BoundRoute{Route: &RouteMetadata{
+				Operation:     &RestOperation{Method: "GET", Path: "/reports", Headers: headers, DisableAuth: true},
+				Package:       "http",
+				HandlerType:   "versionedHandler",
+				HandlerMethod: "Reports",
+			}, Handler: (&versionedHandler{}).Reports}
+		}
+		shared := mux.NewRouter()
+		require.NoError(t, NewRegistry().RegisterBoundRoutesTo(MuxTarget(shared), route("X-API-Version=2")))
+		other := NewRegistry()
+		require.NoError(t, other.RegisterBoundRoutesTo(MuxTarget(mux.NewRouter()), route()))
+		require.NoError(t, other.RegisterBoundRoutesTo(MuxTarget(shared), route()))
+		assert.Equal(t, "v1", serve(shared, "/reports").Body.String())
+	})
+
+	t.Run("requires a target supporting matchers", func(t *testing.T) {
+		err := NewRegistry().RegisterRoutesTo(ServeMuxTarget(http.NewServeMux()), &versionedHandler{}, "./matchers_test.go")
+		assert.ErrorContains(t, err, "router does not support queries, headers, host or schemes matchers")
+	})
+}
@@ -0,0 +1,265 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultBuckets are the latency histogram buckets, in seconds, of routes that do not set metricsBuckets.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// routeMetrics holds the metrics of every route registered through a registry. Routes are keyed
+// by name, method and path, as a method with several annotations has one route name for all.
+type routeMetrics struct {
+	mu     sync.Mutex
+	routes map[metricsKey]*routeStats
+}
+
+type metricsKey struct {
+	name, method, path string
+}
+
+// routeStats holds the metrics of one route.
+type routeStats struct {
+	metricsKey
+	timeout  time.Duration
+	buckets  []float64
+	inFlight atomic.Int64
+	timeouts atomic.Uint64
+
+	mu      sync.Mutex
+	errors  uint64
+	byClass map[string]*latencyHistogram
+}
+
+type latencyHistogram struct {
+	counts []uint64 // per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// route returns the metrics of route, created on first use. Routes registered again on
+// another router share their metrics.
+func (m *routeMetrics) route(route *RouteMetadata) *routeStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := metricsKey{route.RouteName(), route.Operation.Method, route.Operation.Path}
+	if stats, ok := m.routes[key]; ok {
+		return stats
+	}
+	buckets := route.Operation.MetricsBuckets
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	stats := &routeStats{
+		metricsKey: key,
+		timeout:    time.Duration(route.Operation.Timeout) * time.Second,
+		buckets:    buckets,
+		byClass:    make(map[string]*latencyHistogram),
+	}
+	m.routes[key] = stats
+	return stats
+}
+
+type routeStatsKey struct{}
+
+// countTimeout counts a timeout of the request of ctx with the metrics of its route, if any.
+func countTimeout(ctx context.Context) {
+	if stats, ok := ctx.Value(routeStatsKey{}).(*routeStats); ok {
+		stats.timeouts.Add(1)
+	}
+}
+
+func (s *routeStats) observe(status int, latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	class := strconv.Itoa(status/100) + "xx"
+	h, ok := s.byClass[class]
+	if !ok {
+		h = &latencyHistogram{counts: make([]uint64, len(s.buckets))}
+		s.byClass[class] = h
+	}
+	seconds := latency.Seconds()
+	if i := sort.SearchFloat64s(s.buckets, seconds); i < len(s.buckets) {
+		h.counts[i]++
+	}
+	h.count++
+	h.sum += seconds
+	if status >= 500 {
+		s.errors++
+	}
+}
+
+// WithMetrics wraps handler so its requests are counted and timed, unless the route sets disableMetrics.
+func (reg *Registry) WithMetrics(handler http.HandlerFunc, route *RouteMetadata) http.HandlerFunc {
+	if route.Operation.DisableMetrics {
+		return handler
+	}
+	var once sync.Once
+	var stats *routeStats
+	return func(w http.ResponseWriter, r *http.Request) {
+		once.Do(func() { stats = reg.metrics.route(route) })
+		stats.inFlight.Add(1)
+		defer stats.inFlight.Add(-1)
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		handler(rec, r.WithContext(context.WithValue(r.Context(), routeStatsKey{}, stats)))
+		stats.observe(rec.statusCode(), time.Since(start))
+	}
+}
+
+// MetricsHandler serves the metrics of the routes registered through the registry in the
+// Prometheus text exposition format:
+//
+//   - http_route_requests_total and the http_route_request_duration_seconds histogram, by route, method, path and status class;
+//   - http_route_errors_total, requests answered with a 5xx status, and the http_route_in_flight gauge, by route, method and path;
+//   - http_route_timeouts_total, by route, method, path and timeout.
+func (reg *Registry) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		reg.metrics.write(w)
+	})
+}
+
+// MetricsHandler serves the route metrics of the default registry.
+func MetricsHandler() http.Handler {
+	return defaultRegistry.MetricsHandler()
+}
+
+func (m *routeMetrics) write(w io.Writer) {
+	m.mu.Lock()
+	routes := make([]*routeStats, 0, len(m.routes))
+	for _, stats := range m.routes {
+		routes = append(routes, stats)
+	}
+	m.mu.Unlock()
+	sort.Slice(routes, func(i, j int) bool {
+		a, b := routes[i].metricsKey, routes[j].metricsKey
+		if a.name != b.name {
+			return a.name < b.name
+		}
+		if a.method != b.method {
+			return a.method < b.method
+		}
+		return a.path < b.path
+	})
+
+	family := func(name, typ, help string) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+	}
+	family("http_route_requests_total", "counter", "Requests handled by the route.")
+	for _, s := range routes {
+		s.eachClass(func(class string, h *latencyHistogram) {
+			fmt.Fprintf(w, "http_route_requests_total%s %d\n", labels("route", s.name, "method", s.method, "path", s.path, "status_class", class), h.count)
+		})
+	}
+	family("http_route_errors_total", "counter", "Requests answered with a 5xx status.")
+	for _, s := range routes {
+		s.mu.Lock()
+		errors := s.errors
+		s.mu.Unlock()
+		fmt.Fprintf(w, "http_route_errors_total%s %d\n", labels("route", s.name, "method", s.method, "path", s.path), errors)
+	}
+	family("http_route_in_flight", "gauge", "Requests being handled by the route.")
+	for _, s := range routes {
+		fmt.Fprintf(w, "http_route_in_flight%s %d\n", labels("route", s.name, "method", s.method, "path", s.path), s.inFlight.Load())
+	}
+	family("http_route_timeouts_total", "counter", "Requests that exceeded the route timeout.")
+	for _, s := range routes {
+		fmt.Fprintf(w, "http_route_timeouts_total%s %d\n", labels("route", s.name, "method", s.method, "path", s.path, "timeout", s.timeout.String()), s.timeouts.Load())
+	}
+	family("http_route_request_duration_seconds", "histogram", "Latency of the requests handled by the route.")
+	for _, s := range routes {
+		s.eachClass(func(class string, h *latencyHistogram) {
+			var cumulative uint64
+			for i, bound := range s.buckets {
+				cumulative += h.counts[i]
+				fmt.Fprintf(w, "http_route_request_duration_seconds_bucket%s %d\n", labels("route", s.name, "method", s.method, "path", s.path, "status_class", class, "le", formatFloat(bound)), cumulative)
+			}
+			fmt.Fprintf(w, "http_route_request_duration_seconds_bucket%s %d\n", labels("route", s.name, "method", s.method, "path", s.path, "status_class", class, "le", "+Inf"), h.count)
+			fmt.Fprintf(w, "http_route_request_duration_seconds_sum%s %s\n", labels("route", s.name, "method", s.method, "path", s.path, "status_class", class), formatFloat(h.sum))
+			fmt.Fprintf(w, "http_route_request_duration_seconds_count%s %d\n", labels("route", s.name, "method", s.method, "path", s.path, "status_class", class), h.count)
+		})
+	}
+}
+
+// eachClass calls fn with a copy of the histogram of every status class, in order.
+func (s *routeStats) eachClass(fn func(class string, h *latencyHistogram)) {
+	s.mu.Lock()
+	classes := make([]string, 0, len(s.byClass))
+	copies := make(map[string]*latencyHistogram, len(s.byClass))
+	for class, h := range s.byClass {
+		classes = append(classes, class)
+		copies[class] = &latencyHistogram{counts: append([]uint64{}, h.counts...), count: h.count, sum: h.sum}
+	}
+	s.mu.Unlock()
+	sort.Strings(classes)
+	for _, class := range classes {
+		fn(class, copies[class])
+	}
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// labels formats alternating label names and values, e.g. {route="a",method="GET"}.
+func labels(pairs ...string) string {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i := 0; i < len(pairs); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(pairs[i] + `="` + labelEscaper.Replace(pairs[i+1]) + `"`)
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// parseBuckets reads histogram buckets given as numbers of seconds or durations, e.g.
+// [0.1, 0.5, 1] or [100ms, 500ms, 1s]. Buckets must be positive and increasing.
+func parseBuckets(v *annotationValue) ([]float64, error) {
+	if v.kind != arrayValue {
+		return nil, fmt.Errorf("expected array, found %s", v.kind)
+	}
+	buckets := make([]float64, 0, len(v.elems))
+	for _, elem := range v.elems {
+		var bucket float64
+		var err error
+		if elem.kind == numberValue {
+			bucket, err = elem.asFloat()
+		} else {
+			var d time.Duration
+			d, err = elem.asDuration()
+			bucket = d.Seconds()
+		}
+		if err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, bucket)
+	}
+	if err := validateBuckets(buckets); err != nil {
+		return nil, err
+	}
+	return buckets, nil
+}
+
+func validateBuckets(buckets []float64) error {
+	for i, bucket := range buckets {
+		if bucket <= 0 || i > 0 && bucket <= buckets[i-1] {
+			return fmt.Errorf("buckets must be positive and increasing")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package http
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type meteredHandler struct{}
+
+// @RestOperation( method = "GET", path = "/orders/{id}", disableAuth = true, metricsBuckets = [0.1, 1] )
+func (h *meteredHandler) GetOrder(w http.ResponseWriter, r *http.Request) {
+	if PathParam(r, "id") == "fail" {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// @RestOperation( method = "GET", path = "/slow", disableAuth = true, timeout = 1s, metricsBuckets = [100ms, 2s] )
+func (h *meteredHandler) Slow(w http.ResponseWriter, r *http.Request) {
+	<-r.Context().Done()
+}
+
+// @RestOperation( method = "GET", path = "/health", disableAuth = true, disableMetrics = true )
+func (h *meteredHandler) Health(w http.ResponseWriter, r *http.Request) {}
+
+type aliasedHandler struct{}
+
+// @RestOperation( method = "GET", path = "/v1/items", disableAuth = true )
+// @RestOperation( method = "GET", path = "/v2/items", disableAuth = true, timeout = 5 )
+// @RestOperation( method = "POST", path = "/v2/items", disableAuth = true )
+func (h *aliasedHandler) Items(w http.ResponseWriter, r *http.Request) {}
+
+func TestParseMetricsKeys(t *testing.T) {
+	t.Run("parses buckets as seconds or durations", func(t *testing.T) {
+		op, err := ParseRestOperation(`@RestOperation( method = "GET", path = "/", metricsBuckets = [0.05, 250ms, 1s] )`)
+		require.NoError(t, err)
+		assert.Equal(t, []float64{0.05, 0.25, 1}, op.MetricsBuckets)
+	})
+
+	t.Run("rejects unordered buckets", func(t *testing.T) {
+		_, err := ParseRestOperation(`@RestOperation( method = "GET", path = "/", metricsBuckets = [1, 0.5] )`)
+		assert.ErrorContains(t, err, "invalid metricsBuckets value: buckets must be positive and increasing")
+	})
+}
+
+func TestMetrics(t *testing.T) {
+	registry := NewRegistry()
+	router := mux.NewRouter()
+	require.NoError(t, registry.RegisterRoutes(router, &meteredHandler{}, "./metrics_test.go"))
+	serve(router, "/orders/1")
+	serve(router, "/orders/2")
+	serve(router, "/orders/fail")
+	serve(router, "/health")
+	metrics := serve(registry.MetricsHandler(), "/metrics")
+
+	t.Run("serves Prometheus text format", func(t *testing.T) {
+		assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", metrics.Header().Get("Content-Type"))
+		assert.Contains(t, metrics.Body.String(), "# TYPE http_route_requests_total counter\n")
+		assert.Contains(t, metrics.Body.String(), "# TYPE http_route_request_duration_seconds histogram\n")
+	})
+
+	t.Run("counts requests and errors by route, method and status class", func(t *testing.T) {
+		body := metrics.Body.String()
+		assert.Contains(t, body, `http_route_requests_total{route="http.meteredHandler.GetOrder",method="GET",path="/orders/{id}",status_class="2xx"} 2`)
+		assert.Contains(t, body, `http_route_requests_total{route="http.meteredHandler.GetOrder",method="GET",path="/orders/{id}",status_class="5xx"} 1`)
+		assert.Contains(t, body, `http_route_errors_total{route="http.meteredHandler.GetOrder",method="GET",path="/orders/{id}"} 1`)
+		assert.Contains(t, body, `http_route_in_flight{route="http.meteredHandler.GetOrder",method="GET",path="/orders/{id}"} 0`)
+	})
+
+	t.Run("uses the route buckets", func(t *testing.T) {
+		body := metrics.Body.String()
+		assert.Contains(t, body, `http_route_request_duration_seconds_bucket{route="http.meteredHandler.GetOrder",method="GET",path="/orders/{id}",status_class="2xx",le="0.1"} 2`)
+		assert.Contains(t, body, `http_route_request_duration_seconds_bucket{route="http.meteredHandler.GetOrder",method="GET",path="/orders/{id}",status_class="2xx",le="+Inf"} 2`)
+		assert.Contains(t, body, `http_route_request_duration_seconds_count{route="http.meteredHandler.GetOrder",method="GET",path="/orders/{id}",status_class="2xx"} 2`)
+	})
+
+	t.Run("exposes registered routes before their first request", func(t *testing.T) {
+		assert.Contains(t, metrics.Body.String(), `http_route_errors_total{route="http.meteredHandler.Slow",method="GET",path="/slow"} 0`)
+	})
+
+	t.Run("skips routes that opt out", func(t *testing.T) {
+		assert.NotContains(t, metrics.Body.String(), "Health")
+	})
+
+	t.Run("counts timeouts with the route timeout", func(t *testing.T) {
+		if testing.Short() {
+			t.Skip("waits for a timeout")
+		}
+		start := time.Now()
+		assert.Equal(t, http.StatusServiceUnavailable, serve(router, "/slow").Code)
+		assert.GreaterOrEqual(t, time.Since(start), time.Second)
+		body := serve(registry.MetricsHandler(), "/metrics").Body.String()
+		assert.Contains(t, body, `http_route_timeouts_total{route="http.meteredHandler.Slow",method="GET",path="/slow",timeout="1s"} 1`)
+		assert.Contains(t, body, `http_route_request_duration_seconds_bucket{route="http.meteredHandler.Slow",method="GET",path="/slow",status_class="5xx",le="2"} 1`)
+	})
+
+	t.Run("keeps the routes of one method apart", func(t *testing.T) {
+		registry := NewRegistry()
+		router := mux.NewRouter()
+		require.NoError(t, registry.RegisterRoutes(router, &aliasedHandler{}, "./metrics_test.go"))
+		serve(router, "/v2/items")
+		body := serve(registry.MetricsHandler(), "/metrics").Body.String()
+		assert.Contains(t, body, `http_route_timeouts_total{route="http.aliasedHandler.Items",method="GET",path="/v1/items",timeout="0s"} 0`)
+		assert.Contains(t, body, `http_route_timeouts_total{route="http.aliasedHandler.Items",method="GET",path="/v2/items",timeout="5s"} 0`)
+		assert.Contains(t, body, `http_route_requests_total{route="http.aliasedHandler.Items",method="GET",path="/v2/items",status_class="2xx"} 1`)
+		assert.NotContains(t, body, `path="/v1/items",status_class`)
+	})
+
+	t.Run("does not record validated routes", func(t *testing.T) {
+		registry := NewRegistry()
+		require.NoError(t, registry.ValidateRoutes(&meteredHandler{}, "./metrics_test.go"))
+		assert.NotContains(t, serve(registry.MetricsHandler(), "/metrics").Body.String(), "meteredHandler")
+	})
+}
+
+func TestMetricsStreaming(t *testing.T) {
+	op, err := ParseRestOperation(`@RestOperation( method = "GET", path = "/events", disableAuth = true )`)
+	require.NoError(t, err)
+	var flusher, hijacker bool
+	var hijackErr error
+	router := mux.NewRouter()
+	require.NoError(t, NewRegistry().RegisterRoute(router, &RouteMetadata{Operation: op, HandlerMethod: "Events", Package: "http"}, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("data: 1\n\n"))
+		var f http.Flusher
+		if f, flusher = w.(http.Flusher); flusher {
+			f.Flush()
+		}
+		var h http.Hijacker
+		if h, hijacker = w.(http.Hijacker); hijacker {
+			_, _, hijackErr = h.Hijack()
+		}
+	}))
+	res := serve(router, "/events")
+	assert.True(t, flusher)
+	assert.True(t, res.Flushed)
+	assert.True(t, hijacker)
+	assert.ErrorIs(t, hijackErr, http.ErrNotSupported)
+}
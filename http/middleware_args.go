@@ -0,0 +1,220 @@
+package http
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MiddlewareFactory builds a middleware from the arguments of a reference such as
+// "RateLimit(rps=100, burst=20)" and the operation of the route it is applied to.
+// Errors are reported when the route is registered.
+type MiddlewareFactory func(args MiddlewareArgs, op RestOperation) (MiddlewareFunc, error)
+
+// MiddlewareArgs holds the arguments of a middleware reference. Strings, identifiers,
+// booleans, integers (int), floats (float64), durations (time.Duration), arrays ([]any) and
+// objects (map[string]any) are supported.
+type MiddlewareArgs struct {
+	Positional []any
+	Named      map[string]any
+}
+
+// Len returns the total number of arguments.
+func (a MiddlewareArgs) Len() int {
+	return len(a.Positional) + len(a.Named)
+}
+
+// Check returns an error when a named argument is not one of allowed.
+func (a MiddlewareArgs) Check(allowed ...string) error {
+	var unknown []string
+	for name := range a.Named {
+		found := false
+		for _, candidate := range allowed {
+			found = found || candidate == name
+		}
+		if !found {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown argument %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// String returns the named string argument, or def when it is absent.
+func (a MiddlewareArgs) String(name, def string) (string, error) {
+	return namedArg(a, name, def)
+}
+
+// Bool returns the named boolean argument, or def when it is absent.
+func (a MiddlewareArgs) Bool(name string, def bool) (bool, error) {
+	return namedArg(a, name, def)
+}
+
+// Int returns the named integer argument, or def when it is absent.
+func (a MiddlewareArgs) Int(name string, def int) (int, error) {
+	return namedArg(a, name, def)
+}
+
+// Float returns the named numeric argument, or def when it is absent.
+func (a MiddlewareArgs) Float(name string, def float64) (float64, error) {
+	value, ok := a.Named[name]
+	if !ok {
+		return def, nil
+	}
+	if n, ok := value.(int); ok {
+		return float64(n), nil
+	}
+	return namedArg(a, name, def)
+}
+
+// Duration returns the named duration argument, or def when it is absent.
+func (a MiddlewareArgs) Duration(name string, def time.Duration) (time.Duration, error) {
+	return namedArg(a, name, def)
+}
+
+// Strings returns the positional arguments, which must all be strings.
+func (a MiddlewareArgs) Strings() ([]string, error) {
+	result := make([]string, 0, len(a.Positional))
+	for i, value := range a.Positional {
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("argument %d must be a string, got %v", i+1, value)
+		}
+		result = append(result, s)
+	}
+	return result, nil
+}
+
+func namedArg[T any](a MiddlewareArgs, name string, def T) (T, error) {
+	value, ok := a.Named[name]
+	if !ok {
+		return def, nil
+	}
+	typed, ok := value.(T)
+	if !ok {
+		return def, fmt.Errorf("argument %s must be a %T, got %v", name, def, value)
+	}
+	return typed, nil
+}
+
+// parseMiddlewareRef parses a reference of the form Name or Name(arg, key = value, ...).
+func parseMiddlewareRef(ref string) (string, MiddlewareArgs, error) {
+	args := MiddlewareArgs{}
+	p := &annotationParser{lex: &annotationLexer{src: ref, refIdents: true}}
+	if err := p.advance(); err != nil {
+		return "", args, err
+	}
+	if p.tok.kind != tokIdent {
+		return "", args, p.unexpected("middleware name")
+	}
+	name := p.tok.text
+	if err := p.advance(); err != nil {
+		return "", args, err
+	}
+	if p.tok.kind == tokLParen {
+		if err := p.advance(); err != nil {
+			return "", args, err
+		}
+		for p.tok.kind != tokRParen {
+			if err := p.parseMiddlewareArg(&args); err != nil {
+				return "", args, err
+			}
+			if err := p.skipComma(tokRParen); err != nil {
+				return "", args, err
+			}
+		}
+		if err := p.advance(); err != nil {
+			return "", args, err
+		}
+	}
+	if p.tok.kind != tokEOF {
+		return "", args, p.unexpected("end of middleware reference")
+	}
+	return name, args, nil
+}
+
+func (p *annotationParser) parseMiddlewareArg(args *MiddlewareArgs) error {
+	var value *annotationValue
+	if p.tok.kind == tokIdent {
+		ident := p.tok
+		if err := p.advance(); err != nil {
+			return err
+		}
+		if p.tok.kind == tokAssign {
+			if err := p.advance(); err != nil {
+				return err
+			}
+			if _, exists := args.Named[ident.text]; exists {
+				return p.errorf(ident.offset, "duplicate argument %s", ident.text)
+			}
+			value, err := p.parseValue()
+			if err != nil {
+				return err
+			}
+			if args.Named == nil {
+				args.Named = make(map[string]any)
+			}
+			args.Named[ident.text] = value.goValue()
+			return nil
+		}
+		value = &annotationValue{kind: identValue, text: ident.text, offset: ident.offset}
+		if ident.text == "true" || ident.text == "false" {
+			value.kind = boolValue
+		}
+	} else {
+		var err error
+		if value, err = p.parseValue(); err != nil {
+			return err
+		}
+	}
+	if len(args.Named) > 0 {
+		return p.errorf(value.offset, "positional argument after named arguments")
+	}
+	args.Positional = append(args.Positional, value.goValue())
+	return nil
+}
+
+// goValue converts a parsed value to its Go representation. Identifiers become strings.
+func (v *annotationValue) goValue() any {
+	switch v.kind {
+	case boolValue:
+		return v.text == "true"
+	case numberValue:
+		if n, err := strconv.Atoi(v.text); err == nil {
+			return n
+		}
+		f, _ := strconv.ParseFloat(v.text, 64)
+		return f
+	case durationValue:
+		d, _ := time.ParseDuration(v.text)
+		return d
+	case arrayValue:
+		elems := make([]any, len(v.elems))
+		for i, elem := range v.elems {
+			elems[i] = elem.goValue()
+		}
+		return elems
+	case objectValue:
+		fields := make(map[string]any, len(v.fields))
+		for _, field := range v.fields {
+			fields[field.key] = field.value.goValue()
+		}
+		return fields
+	}
+	return v.text
+}
+
+// validateMiddlewareRefs checks the syntax of every middleware reference.
+func validateMiddlewareRefs(refs []string) error {
+	for _, ref := range refs {
+		if _, _, err := parseMiddlewareRef(ref); err != nil {
+			return fmt.Errorf("%q: %w", ref, err)
+		}
+	}
+	return nil
+}
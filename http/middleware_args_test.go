@@ -0,0 +1,145 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMiddlewareRef(t *testing.T) {
+	t.Run("parses plain name", func(t *testing.T) {
+		name, args, err := parseMiddlewareRef("request-id")
+		require.NoError(t, err)
+		assert.Equal(t, "request-id", name)
+		assert.Equal(t, 0, args.Len())
+	})
+
+	t.Run("parses named arguments", func(t *testing.T) {
+		name, args, err := parseMiddlewareRef(`RateLimit(rps=100, burst=20, window=1m, ratio=0.5, strict=true)`)
+		require.NoError(t, err)
+		assert.Equal(t, "RateLimit", name)
+		assert.Equal(t, map[string]any{"rps": 100, "burst": 20, "window": time.Minute, "ratio": 0.5, "strict": true}, args.Named)
+	})
+
+	t.Run("parses positional arguments", func(t *testing.T) {
+		name, args, err := parseMiddlewareRef(`RequireRole("admin" "ops", ["a", "b"])`)
+		require.NoError(t, err)
+		assert.Equal(t, "RequireRole", name)
+		assert.Equal(t, []any{"admin", "ops", []any{"a", "b"}}, args.Positional)
+	})
+
+	t.Run("parses object arguments", func(t *testing.T) {
+		_, args, err := parseMiddlewareRef(`Limit({burst = 2}, cfg = {rps = 1, window = 1m, paths = [{prefix = "/a"}]})`)
+		require.NoError(t, err)
+		assert.Equal(t, []any{map[string]any{"burst": 2}}, args.Positional)
+		assert.Equal(t, map[string]any{"cfg": map[string]any{"rps": 1, "window": time.Minute, "paths": []any{map[string]any{"prefix": "/a"}}}}, args.Named)
+		_, _, err = parseMiddlewareRef(`Limit(cfg = {rps = 1, rps = 2})`)
+		assert.ErrorIs(t, err, ErrInvalidFormat)
+	})
+
+	t.Run("rejects positional argument after named", func(t *testing.T) {
+		_, _, err := parseMiddlewareRef(`RateLimit(rps=1, 2)`)
+		assert.ErrorContains(t, err, "positional argument after named arguments")
+	})
+
+	t.Run("rejects trailing text", func(t *testing.T) {
+		_, _, err := parseMiddlewareRef(`RateLimit(rps=1) extra`)
+		assert.ErrorContains(t, err, "expected end of middleware reference")
+	})
+
+	t.Run("rejects unterminated arguments", func(t *testing.T) {
+		_, _, err := parseMiddlewareRef(`RateLimit(rps=1`)
+		assert.ErrorIs(t, err, ErrInvalidFormat)
+	})
+}
+
+func TestMiddlewareArgs(t *testing.T) {
+	args := MiddlewareArgs{Positional: []any{"admin", 3}, Named: map[string]any{"rps": 100, "ratio": 0.5, "name": "x"}}
+
+	t.Run("returns typed named arguments", func(t *testing.T) {
+		rps, err := args.Int("rps", 1)
+		require.NoError(t, err)
+		assert.Equal(t, 100, rps)
+		ratio, err := args.Float("rps", 0)
+		require.NoError(t, err)
+		assert.Equal(t, 100.0, ratio)
+		burst, err := args.Int("burst", 5)
+		require.NoError(t, err)
+		assert.Equal(t, 5, burst)
+	})
+
+	t.Run("returns error for wrong type", func(t *testing.T) {
+		_, err := args.Int("name", 0)
+		assert.ErrorContains(t, err, "argument name must be a int")
+		_, err = args.Strings()
+		assert.ErrorContains(t, err, "argument 2 must be a string")
+	})
+
+	t.Run("checks allowed names", func(t *testing.T) {
+		assert.NoError(t, args.Check("rps", "ratio", "name"))
+		assert.ErrorContains(t, args.Check("rps"), "unknown argument name, ratio")
+	})
+}
+
+func TestMiddlewareFactory(t *testing.T) {
+	requireRole := func(args MiddlewareArgs, op RestOperation) (MiddlewareFunc, error) {
+		roles, err := args.Strings()
+		if err != nil {
+			return nil, err
+		}
+		if len(roles) == 0 {
+			return nil, errors.New("at least one role is required")
+		}
+		return func(next http.HandlerFunc) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Roles", roles[0])
+				w.Header().Set("X-Path", op.Path)
+				next(w, r)
+			}
+		}, nil
+	}
+
+	t.Run("builds middleware from reference arguments", func(t *testing.T) {
+		registry := NewRegistry()
+		require.NoError(t, registry.RegisterMiddlewareFactory("RequireRole", requireRole))
+		op, err := ParseRestOperation(`@RestOperation( method = "GET", path = "/admin", middlewares = ["RequireRole(\"admin\")"] )`)
+		require.NoError(t, err)
+		wrapped, err := registry.ApplyMiddlewares(func(w http.ResponseWriter, r *http.Request) {}, *op)
+		require.NoError(t, err)
+		res := httptest.NewRecorder()
+		wrapped(res, httptest.NewRequest(http.MethodGet, "/admin", nil))
+		assert.Equal(t, "admin", res.Header().Get("X-Roles"))
+		assert.Equal(t, "/admin", res.Header().Get("X-Path"))
+	})
+
+	t.Run("reports factory errors at registration", func(t *testing.T) {
+		registry := NewRegistry()
+		require.NoError(t, registry.RegisterMiddlewareFactory("RequireRole", requireRole))
+		_, err := registry.ApplyMiddlewares(func(w http.ResponseWriter, r *http.Request) {}, RestOperation{Middlewares: []string{"RequireRole()"}})
+		assert.ErrorContains(t, err, "middleware RequireRole(): at least one role is required")
+	})
+
+	t.Run("rejects arguments for plain middleware", func(t *testing.T) {
+		registry := NewRegistry()
+		require.NoError(t, registry.RegisterMiddleware("auth", func(next http.HandlerFunc) http.HandlerFunc { return next }))
+		_, err := registry.ApplyMiddlewares(func(w http.ResponseWriter, r *http.Request) {}, RestOperation{Middlewares: []string{"auth(strict=true)"}})
+		assert.ErrorContains(t, err, "middleware auth does not take arguments")
+	})
+
+	t.Run("rejects factory with name of plain middleware", func(t *testing.T) {
+		registry := NewRegistry()
+		require.NoError(t, registry.RegisterMiddleware("auth", func(next http.HandlerFunc) http.HandlerFunc { return next }))
+		err := registry.RegisterMiddlewareFactory("auth", requireRole)
+		assert.ErrorContains(t, err, "already registered")
+	})
+
+	t.Run("reports malformed reference when parsing annotation", func(t *testing.T) {
+		_, err := ParseRestOperation(`@RestOperation( method = "GET", path = "/", middlewares = ["RateLimit(rps=)"] )`)
+		assert.ErrorContains(t, err, `invalid middlewares value: "RateLimit(rps=)"`)
+	})
+}
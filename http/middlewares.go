@@ -3,32 +3,44 @@ package http
 import (
 	"fmt"
 	"net/http"
-	"sync"
 )
 
 type MiddlewareFunc func(http.HandlerFunc) http.HandlerFunc
 
-var (
-	middlewares = make(map[string]MiddlewareFunc)
-	mu          sync.RWMutex
-)
-
 // RegisterMiddleware registers a middleware with a given name.
-func RegisterMiddleware(name string, middleware MiddlewareFunc) error {
-	mu.Lock()
-	defer mu.Unlock()
-	if _, exists := middlewares[name]; exists {
+func (reg *Registry) RegisterMiddleware(name string, middleware MiddlewareFunc) error {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	if reg.hasMiddleware(name) {
+		return fmt.Errorf("middleware %s already registered", name)
+	}
+	reg.middlewares[name] = middleware
+	return nil
+}
+
+// RegisterMiddlewareFactory registers a factory for middlewares referenced with arguments,
+// e.g. "RateLimit(rps=100, burst=20)" or "RequireRole(\"admin\")".
+func (reg *Registry) RegisterMiddlewareFactory(name string, factory MiddlewareFactory) error {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	if reg.hasMiddleware(name) {
 		return fmt.Errorf("middleware %s already registered", name)
 	}
-	middlewares[name] = middleware
+	reg.factories[name] = factory
 	return nil
 }
 
+func (reg *Registry) hasMiddleware(name string) bool {
+	_, plain := reg.middlewares[name]
+	_, factory := reg.factories[name]
+	return plain || factory
+}
+
 // GetMiddleware retrieves a middleware by name.
-func GetMiddleware(name string) (MiddlewareFunc, error) {
-	mu.RLock()
-	defer mu.RUnlock()
-	middleware, exists := middlewares[name]
+func (reg *Registry) GetMiddleware(name string) (MiddlewareFunc, error) {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	middleware, exists := reg.middlewares[name]
 	if !exists {
 		return nil, fmt.Errorf("middleware %s not found", name)
 	}
@@ -36,12 +48,12 @@ func GetMiddleware(name string) (MiddlewareFunc, error) {
 }
 
 // GetMiddlewares retrieves multiple middlewares by names.
-func GetMiddlewares(names []string) ([]MiddlewareFunc, error) {
-	mu.RLock()
-	defer mu.RUnlock()
+func (reg *Registry) GetMiddlewares(names []string) ([]MiddlewareFunc, error) {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
 	result := make([]MiddlewareFunc, 0, len(names))
 	for _, name := range names {
-		middleware, exists := middlewares[name]
+		middleware, exists := reg.middlewares[name]
 		if !exists {
 			return nil, fmt.Errorf("middleware %s not found", name)
 		}
@@ -51,21 +63,85 @@ func GetMiddlewares(names []string) ([]MiddlewareFunc, error) {
 }
 
 // ClearMiddlewares clears all registered middlewares (useful for testing).
-func ClearMiddlewares() {
-	mu.Lock()
-	defer mu.Unlock()
-	middlewares = make(map[string]MiddlewareFunc)
+func (reg *Registry) ClearMiddlewares() {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.middlewares = make(map[string]MiddlewareFunc)
+	reg.factories = make(map[string]MiddlewareFactory)
 }
 
-func ApplyMiddlewares(handler http.HandlerFunc, restOperation RestOperation) (http.HandlerFunc, error) {
+// ApplyMiddlewares wraps handler with the middlewares referenced by the operation, the first
+// reference being the outermost. References with arguments are built by their factory.
+// With EnableMiddlewareSpans, each middleware runs in a child span.
+func (reg *Registry) ApplyMiddlewares(handler http.HandlerFunc, restOperation RestOperation) (http.HandlerFunc, error) {
+	reg.mu.RLock()
+	spans := reg.middlewareSpans
+	reg.mu.RUnlock()
 	// Apply middlewares in the order they were registered
-	for i:=len(restOperation.Middlewares)-1; i>=0; i-- {
-		middleware, err := GetMiddleware(restOperation.Middlewares[i])
+	for i := len(restOperation.Middlewares) - 1; i >= 0; i-- {
+		ref := restOperation.Middlewares[i]
+		middleware, err := reg.resolveMiddleware(ref, restOperation)
 		if err != nil {
 			return nil, err
 		}
 		handler = middleware(handler)
+		if spans {
+			handler = withMiddlewareSpan(reg.tracer(), ref, handler)
+		}
 	}
 	return handler, nil
 }
 
+func (reg *Registry) resolveMiddleware(ref string, restOperation RestOperation) (MiddlewareFunc, error) {
+	name, args, err := parseMiddlewareRef(ref)
+	if err != nil {
+		return nil, fmt.Errorf("invalid middleware reference %q: %w", ref, err)
+	}
+	reg.mu.RLock()
+	middleware, plain := reg.middlewares[name]
+	factory, hasFactory := reg.factories[name]
+	reg.mu.RUnlock()
+	switch {
+	case plain && args.Len() > 0:
+		return nil, fmt.Errorf("middleware %s does not take arguments", name)
+	case plain:
+		return middleware, nil
+	case hasFactory:
+		middleware, err := factory(args, restOperation)
+		if err != nil {
+			return nil, fmt.Errorf("middleware %s: %w", ref, err)
+		}
+		return middleware, nil
+	}
+	return nil, fmt.Errorf("middleware %s not found", name)
+}
+
+// RegisterMiddleware registers a middleware with a given name on the default registry.
+func RegisterMiddleware(name string, middleware MiddlewareFunc) error {
+	return defaultRegistry.RegisterMiddleware(name, middleware)
+}
+
+// GetMiddleware retrieves a middleware by name from the default registry.
+func GetMiddleware(name string) (MiddlewareFunc, error) {
+	return defaultRegistry.GetMiddleware(name)
+}
+
+// GetMiddlewares retrieves multiple middlewares by names from the default registry.
+func GetMiddlewares(names []string) ([]MiddlewareFunc, error) {
+	return defaultRegistry.GetMiddlewares(names)
+}
+
+// ClearMiddlewares clears all middlewares of the default registry (useful for testing).
+func ClearMiddlewares() {
+	defaultRegistry.ClearMiddlewares()
+}
+
+// ApplyMiddlewares wraps handler with middlewares of the default registry.
+func ApplyMiddlewares(handler http.HandlerFunc, restOperation RestOperation) (http.HandlerFunc, error) {
+	return defaultRegistry.ApplyMiddlewares(handler, restOperation)
+}
+
+// RegisterMiddlewareFactory registers a middleware factory on the default registry.
+func RegisterMiddlewareFactory(name string, factory MiddlewareFactory) error {
+	return defaultRegistry.RegisterMiddlewareFactory(name, factory)
+}
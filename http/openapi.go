@@ -0,0 +1,206 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OpenAPIVersion is the OpenAPI specification version of generated documents.
+const OpenAPIVersion = "3.1.0"
+
+var pathParamRegex = regexp.MustCompile(`\{([^{}:]+)(?::((?:[^{}]|\{[^{}]*\})*))?\}`)
+
+// OpenAPIOptions configures GenerateOpenAPI. When SecuritySchemes is set every scheme
+// becomes a global security requirement, which routes with disableAuth = true override.
+type OpenAPIOptions struct {
+	Title           string
+	Version         string
+	Description     string
+	SecuritySchemes map[string]*OpenAPISecurityScheme
+}
+
+// OpenAPIDocument is the root of an OpenAPI 3.1 document.
+type OpenAPIDocument struct {
+	OpenAPI    string                     `json:"openapi" yaml:"openapi"`
+	Info       OpenAPIInfo                `json:"info" yaml:"info"`
+	Paths      map[string]OpenAPIPathItem `json:"paths" yaml:"paths"`
+	Components *OpenAPIComponents         `json:"components,omitempty" yaml:"components,omitempty"`
+	Security   []map[string][]string      `json:"security,omitempty" yaml:"security,omitempty"`
+}
+
+type OpenAPIInfo struct {
+	Title       string `json:"title" yaml:"title"`
+	Version     string `json:"version" yaml:"version"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+type OpenAPIComponents struct {
+	SecuritySchemes map[string]*OpenAPISecurityScheme `json:"securitySchemes,omitempty" yaml:"securitySchemes,omitempty"`
+}
+
+type OpenAPISecurityScheme struct {
+	Type         string `json:"type" yaml:"type"`
+	Scheme       string `json:"scheme,omitempty" yaml:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty" yaml:"bearerFormat,omitempty"`
+	Name         string `json:"name,omitempty" yaml:"name,omitempty"`
+	In           string `json:"in,omitempty" yaml:"in,omitempty"`
+}
+
+// OpenAPIPathItem maps lower-case HTTP methods to operations.
+type OpenAPIPathItem map[string]*OpenAPIOperation
+
+type OpenAPIOperation struct {
+	OperationID string                      `json:"operationId" yaml:"operationId"`
+	Summary     string                      `json:"summary,omitempty" yaml:"summary,omitempty"`
+	Description string                      `json:"description,omitempty" yaml:"description,omitempty"`
+	Tags        []string                    `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Parameters  []*OpenAPIParameter         `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+	Responses   map[string]*OpenAPIResponse `json:"responses" yaml:"responses"`
+	Security    *[]map[string][]string      `json:"security,omitempty" yaml:"security,omitempty"`
+}
+
+type OpenAPIParameter struct {
+	Name     string         `json:"name" yaml:"name"`
+	In       string         `json:"in" yaml:"in"`
+	Required bool           `json:"required" yaml:"required"`
+	Schema   *OpenAPISchema `json:"schema" yaml:"schema"`
+}
+
+type OpenAPISchema struct {
+	Type    string `json:"type,omitempty" yaml:"type,omitempty"`
+	Format  string `json:"format,omitempty" yaml:"format,omitempty"`
+	Pattern string `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+}
+
+type OpenAPIResponse struct {
+	Description string `json:"description" yaml:"description"`
+}
+
+// GenerateOpenAPI builds an OpenAPI 3.1 document describing routes. Path parameters are
+// inferred from {name} and {name:pattern} segments, and the handler doc comment becomes
+// the operation summary (first sentence) and description (the rest). Operation IDs are route
+// names, suffixed with the method and path when a handler has several operations. Routes with
+// the same method and path, e.g. differing only by matchers, cannot be described and fail.
+func GenerateOpenAPI(routes []*RouteMetadata, options OpenAPIOptions) (*OpenAPIDocument, error) {
+	doc := &OpenAPIDocument{
+		OpenAPI: OpenAPIVersion,
+		Info:    OpenAPIInfo{Title: options.Title, Version: options.Version, Description: options.Description},
+		Paths:   make(map[string]OpenAPIPathItem),
+	}
+	if len(options.SecuritySchemes) > 0 {
+		doc.Components = &OpenAPIComponents{SecuritySchemes: options.SecuritySchemes}
+		names := make([]string, 0, len(options.SecuritySchemes))
+		for name := range options.SecuritySchemes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			doc.Security = append(doc.Security, map[string][]string{name: {}})
+		}
+	}
+	names := make(map[string]int)
+	for _, route := range routes {
+		names[route.RouteName()]++
+	}
+	ids := make(map[string]bool)
+	for _, route := range routes {
+		path, params := openAPIPath(route.Operation.Path)
+		method := strings.ToLower(route.Operation.Method)
+		item, ok := doc.Paths[path]
+		if !ok {
+			item = OpenAPIPathItem{}
+			doc.Paths[path] = item
+		}
+		if other, ok := item[method]; ok {
+			return nil, &RouteError{Route: route, Err: fmt.Errorf("duplicate operation %s %s, already described by %s", route.Operation.Method, path, other.OperationID)}
+		}
+		id := route.RouteName()
+		if names[id] > 1 {
+			id += "_" + method
+			if suffix := strings.Trim(nonIdentifierRegex.ReplaceAllString(path, "_"), "_"); suffix != "" {
+				id += "_" + suffix
+			}
+		}
+		for base, n := id, 2; ids[id]; n++ {
+			id = fmt.Sprintf("%s_%d", base, n)
+		}
+		ids[id] = true
+		summary, description := splitDoc(route.Doc)
+		op := &OpenAPIOperation{
+			OperationID: id,
+			Summary:     summary,
+			Description: description,
+			Parameters:  params,
+			Responses:   map[string]*OpenAPIResponse{"default": {Description: "Default response"}},
+		}
+		if route.HandlerType != "" {
+			op.Tags = []string{route.HandlerType}
+		}
+		if route.Operation.DisableAuth {
+			op.Security = &[]map[string][]string{}
+		}
+		item[method] = op
+	}
+	return doc, nil
+}
+
+// nonIdentifierRegex matches the characters of a path left out of operation IDs.
+var nonIdentifierRegex = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// JSON encodes the document as indented JSON.
+func (d *OpenAPIDocument) JSON() ([]byte, error) {
+	return json.MarshalIndent(d, "", "  ")
+}
+
+// YAML encodes the document as YAML.
+func (d *OpenAPIDocument) YAML() ([]byte, error) {
+	return yaml.Marshal(d)
+}
+
+// openAPIPath converts a route template to OpenAPI form and returns its path parameters.
+func openAPIPath(template string) (string, []*OpenAPIParameter) {
+	var params []*OpenAPIParameter
+	path := pathParamRegex.ReplaceAllStringFunc(template, func(segment string) string {
+		match := pathParamRegex.FindStringSubmatch(segment)
+		name := strings.TrimSpace(match[1])
+		schema := &OpenAPISchema{Type: "string"}
+		switch pattern := match[2]; {
+		case pattern == "int":
+			schema.Type = "integer"
+		case pattern == "uuid":
+			schema.Format = "uuid"
+		case strings.HasPrefix(pattern, "regex(") && strings.HasSuffix(pattern, ")"):
+			schema.Pattern = "^" + pattern[len("regex("):len(pattern)-1] + "$"
+		case pattern != "":
+			schema.Pattern = "^" + pattern + "$"
+		}
+		params = append(params, &OpenAPIParameter{Name: name, In: "path", Required: true, Schema: schema})
+		return "{" + name + "}"
+	})
+	return path, params
+}
+
+// splitDoc splits a doc comment into its first sentence and the remaining text.
+// Line breaks inside a paragraph are folded into spaces.
+func splitDoc(doc string) (string, string) {
+	var paragraphs []string
+	for _, paragraph := range strings.Split(strings.TrimSpace(doc), "\n\n") {
+		if paragraph = strings.Join(strings.Fields(paragraph), " "); paragraph != "" {
+			paragraphs = append(paragraphs, paragraph)
+		}
+	}
+	if len(paragraphs) == 0 {
+		return "", ""
+	}
+	summary, rest := paragraphs[0], paragraphs[1:]
+	if i := strings.Index(summary, ". "); i >= 0 {
+		rest = append([]string{summary[i+2:]}, rest...)
+		summary = summary[:i]
+	}
+	return strings.TrimSuffix(summary, "."), strings.Join(rest, "\n\n")
+}
@@ -0,0 +1,133 @@
+package http
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestGenerateOpenAPI(t *testing.T) {
+	content := `package api
+
+// GetUser returns a single user. It looks the user up by id
+// and fails when it does not exist.
+//
+// Deleted users are not returned.
+// @RestOperation( method = "GET", path = "/users/{id:[0-9]+}/posts/{slug}" )
+func (s *Users) GetUser() {}
+
+// CreateUser creates a user
+// @RestOperation( method = "POST", path = "/users", disableAuth = true )
+func (s *Users) CreateUser() {}
+
+type Users struct{}
+`
+	tmpFile := createTempFile(t, content)
+	defer os.Remove(tmpFile)
+	routes, err := ParseRouteMetadata(tmpFile)
+	require.NoError(t, err)
+	doc, err := GenerateOpenAPI(routes, OpenAPIOptions{
+		Title:           "Users",
+		Version:         "1.0.0",
+		SecuritySchemes: map[string]*OpenAPISecurityScheme{"bearerAuth": {Type: "http", Scheme: "bearer"}},
+	})
+	require.NoError(t, err)
+
+	t.Run("describes operations with inferred path parameters", func(t *testing.T) {
+		assert.Equal(t, "3.1.0", doc.OpenAPI)
+		require.Contains(t, doc.Paths, "/users/{id}/posts/{slug}")
+		get := doc.Paths["/users/{id}/posts/{slug}"]["get"]
+		require.NotNil(t, get)
+		assert.Equal(t, "api.Users.GetUser", get.OperationID)
+		assert.Equal(t, []string{"Users"}, get.Tags)
+		require.Len(t, get.Parameters, 2)
+		assert.Equal(t, "id", get.Parameters[0].Name)
+		assert.Equal(t, "path", get.Parameters[0].In)
+		assert.True(t, get.Parameters[0].Required)
+		assert.Equal(t, "^[0-9]+$", get.Parameters[0].Schema.Pattern)
+		assert.Equal(t, "slug", get.Parameters[1].Name)
+		assert.Nil(t, get.Security)
+	})
+
+	t.Run("describes typed path parameters", func(t *testing.T) {
+		path, params := openAPIPath("/orders/{id:int}/{uid:uuid}/{slug:regex([a-z]+)}")
+		assert.Equal(t, "/orders/{id}/{uid}/{slug}", path)
+		require.Len(t, params, 3)
+		assert.Equal(t, &OpenAPISchema{Type: "integer"}, params[0].Schema)
+		assert.Equal(t, &OpenAPISchema{Type: "string", Format: "uuid"}, params[1].Schema)
+		assert.Equal(t, &OpenAPISchema{Type: "string", Pattern: "^[a-z]+$"}, params[2].Schema)
+	})
+
+	t.Run("uses doc comment as summary and description", func(t *testing.T) {
+		get := doc.Paths["/users/{id}/posts/{slug}"]["get"]
+		assert.Equal(t, "GetUser returns a single user", get.Summary)
+		assert.Equal(t, "It looks the user up by id and fails when it does not exist.\n\nDeleted users are not returned.", get.Description)
+		post := doc.Paths["/users"]["post"]
+		assert.Equal(t, "CreateUser creates a user", post.Summary)
+		assert.Empty(t, post.Description)
+	})
+
+	t.Run("maps disableAuth to empty security", func(t *testing.T) {
+		data, err := doc.JSON()
+		require.NoError(t, err)
+		var decoded map[string]any
+		require.NoError(t, json.Unmarshal(data, &decoded))
+		post := decoded["paths"].(map[string]any)["/users"].(map[string]any)["post"].(map[string]any)
+		assert.Equal(t, []any{}, post["security"])
+		assert.Equal(t, []any{map[string]any{"bearerAuth": []any{}}}, decoded["security"])
+	})
+
+	t.Run("encodes YAML", func(t *testing.T) {
+		data, err := doc.YAML()
+		require.NoError(t, err)
+		var decoded map[string]any
+		require.NoError(t, yaml.Unmarshal(data, &decoded))
+		assert.Equal(t, "3.1.0", decoded["openapi"])
+		post := decoded["paths"].(map[string]any)["/users"].(map[string]any)["post"].(map[string]any)
+		assert.Equal(t, []any{}, post["security"])
+	})
+
+	t.Run("suffixes operation IDs of handlers with several operations", func(t *testing.T) {
+		tmpFile := createTempFile(t, `package p
+
+// @RestOperation( method = "GET", path = "/a" )
+// @RestOperation( method = "GET", path = "/b/{id}" )
+func (h *H) Get() {}
+
+// @RestOperation( method = "GET", path = "/" )
+func (h *H) Root() {}
+
+type H struct{}
+`)
+		defer os.Remove(tmpFile)
+		routes, err := ParseRouteMetadata(tmpFile)
+		require.NoError(t, err)
+		doc, err := GenerateOpenAPI(routes, OpenAPIOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, "p.H.Get_get_a", doc.Paths["/a"]["get"].OperationID)
+		assert.Equal(t, "p.H.Get_get_b_id", doc.Paths["/b/{id}"]["get"].OperationID)
+		assert.Equal(t, "p.H.Root", doc.Paths["/"]["get"].OperationID)
+	})
+
+	t.Run("rejects operations with the same method and path", func(t *testing.T) {
+		tmpFile := createTempFile(t, `package p
+
+// @RestOperation( method = "GET", path = "/a", host = "a.example" )
+func (h *H) A() {}
+
+// @RestOperation( method = "GET", path = "/a", host = "b.example" )
+func (h *H) B() {}
+
+type H struct{}
+`)
+		defer os.Remove(tmpFile)
+		routes, err := ParseRouteMetadata(tmpFile)
+		require.NoError(t, err)
+		_, err = GenerateOpenAPI(routes, OpenAPIOptions{})
+		assert.ErrorContains(t, err, "duplicate operation GET /a, already described by p.H.A")
+	})
+}
@@ -0,0 +1,231 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"go/build"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// ParseRouteMetadataDir parses every non-test Go file of the package in dir and records
+// the source file and import path of each route.
+func ParseRouteMetadataDir(dir string) ([]*RouteMetadata, error) {
+	return ParsePackages(dir)
+}
+
+// ParsePackages parses route metadata from each pattern, which may be a Go file, a directory,
+// a glob such as "./api/*.go" or a package pattern such as "./api/...".
+func ParsePackages(patterns ...string) ([]*RouteMetadata, error) {
+	var files []string
+	seen := make(map[string]bool)
+	add := func(file string) {
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			abs = file
+		}
+		if !seen[abs] {
+			seen[abs] = true
+			files = append(files, file)
+		}
+	}
+	for _, pattern := range patterns {
+		matched, err := expandPattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range matched {
+			add(file)
+		}
+	}
+	modules := make(map[string]string)
+	var routes []*RouteMetadata
+	for _, group := range groupByDir(files, filepath.Dir) {
+		parsed, err := parseRouteFiles(group.files, nil)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", group.dir, err)
+		}
+		importPath := importPathOf(group.dir, modules)
+		for _, route := range parsed {
+			route.ImportPath = importPath
+		}
+		routes = append(routes, parsed...)
+	}
+	return routes, nil
+}
+
+// ParseRouteMetadataFS parses the non-test Go files of fsys matching pattern, see fs.Glob, so
+// handler sources embedded with //go:embed can be used independently of the working directory.
+// Import paths are not known and are left empty.
+func ParseRouteMetadataFS(fsys fs.FS, pattern string) ([]*RouteMetadata, error) {
+	matches, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %s: %w", pattern, err)
+	}
+	var files []string
+	for _, match := range matches {
+		if strings.HasSuffix(match, ".go") && !strings.HasSuffix(match, "_test.go") {
+			files = append(files, match)
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("failed to parse file: no Go files match %s", pattern)
+	}
+	readFile := func(name string) ([]byte, error) { return fs.ReadFile(fsys, name) }
+	var routes []*RouteMetadata
+	for _, group := range groupByDir(files, path.Dir) {
+		parsed, err := parseRouteFiles(group.files, readFile)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", group.dir, err)
+		}
+		routes = append(routes, parsed...)
+	}
+	return routes, nil
+}
+
+type dirFiles struct {
+	dir   string
+	files []string
+}
+
+// groupByDir groups files by directory, keeping the order of first appearance. Files of the
+// same directory are parsed together so controllers apply across files.
+func groupByDir(files []string, dirOf func(string) string) []*dirFiles {
+	var groups []*dirFiles
+	byDir := make(map[string]*dirFiles)
+	for _, file := range files {
+		dir := dirOf(file)
+		group, ok := byDir[dir]
+		if !ok {
+			group = &dirFiles{dir: dir}
+			byDir[dir] = group
+			groups = append(groups, group)
+		}
+		group.files = append(group.files, file)
+	}
+	return groups
+}
+
+func expandPattern(pattern string) ([]string, error) {
+	if root, ok := strings.CutSuffix(pattern, "..."); ok {
+		root = strings.TrimSuffix(root, "/")
+		if root == "" || root == "." {
+			root = "."
+		}
+		return walkPackages(root)
+	}
+	if strings.ContainsAny(pattern, "*?[") {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %s: %w", pattern, err)
+		}
+		var files []string
+		for _, match := range matches {
+			if strings.HasSuffix(match, ".go") && !strings.HasSuffix(match, "_test.go") {
+				files = append(files, match)
+			}
+		}
+		return files, nil
+	}
+	info, err := os.Stat(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse file: %w", err)
+	}
+	if !info.IsDir() {
+		return []string{pattern}, nil
+	}
+	return packageFiles(pattern)
+}
+
+// packageFiles lists the Go files of the package in dir that match the current build context.
+func packageFiles(dir string) ([]string, error) {
+	pkg, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load package %s: %w", dir, err)
+	}
+	files := make([]string, 0, len(pkg.GoFiles))
+	for _, file := range pkg.GoFiles {
+		files = append(files, filepath.Join(dir, file))
+	}
+	return files, nil
+}
+
+func walkPackages(root string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		name := d.Name()
+		if p != root && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata" || name == "vendor") {
+			return filepath.SkipDir
+		}
+		if p != root && isModuleRoot(p) {
+			// Nested modules are not part of the pattern, as with the go command.
+			return filepath.SkipDir
+		}
+		pkgFiles, err := packageFiles(p)
+		var noGo *build.NoGoError
+		if errors.As(err, &noGo) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		files = append(files, pkgFiles...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// importPathOf resolves the import path of dir from the nearest go.mod. It returns
+// an empty string when dir is not inside a module. Module roots are cached in modules.
+func importPathOf(dir string, modules map[string]string) string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for root := abs; ; root = filepath.Dir(root) {
+		modulePath, ok := modules[root]
+		if !ok {
+			modulePath = readModulePath(filepath.Join(root, "go.mod"))
+			modules[root] = modulePath
+		}
+		if modulePath != "" {
+			rel, err := filepath.Rel(root, abs)
+			if err != nil {
+				return ""
+			}
+			return path.Join(modulePath, filepath.ToSlash(rel))
+		}
+		if filepath.Dir(root) == root {
+			return ""
+		}
+	}
+}
+
+// readModulePath returns the module path declared by goMod, or "" when it cannot be read.
+func readModulePath(goMod string) string {
+	data, err := os.ReadFile(goMod)
+	if err != nil {
+		return ""
+	}
+	return modfile.ModulePath(data)
+}
+
+// isModuleRoot reports whether dir has a go.mod file.
+func isModuleRoot(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, "go.mod"))
+	return err == nil
+}
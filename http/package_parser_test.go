@@ -0,0 +1,189 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePackages(t *testing.T) {
+	root := createTempModule(t, map[string]string{
+		"go.mod": "module example.com/svc // service API\n\ngo 1.22\n",
+		"api/users.go": `package api
+
+// @RestOperation( method = "GET", path = "/users" )
+func (s *Users) List() {}
+
+type Users struct{}
+`,
+		"api/orders.go": `package api
+
+// @RestOperation( method = "GET", path = "/orders" )
+func (s *Orders) List() {}
+
+type Orders struct{}
+`,
+		"api/users_test.go": `package api
+
+// @RestOperation( method = "GET", path = "/ignored" )
+func (s *Users) Ignored() {}
+`,
+		"api/v2/users.go": `package v2
+
+// @RestOperation( method = "GET", path = "/v2/users" )
+func (s *Users) List() {}
+
+type Users struct{}
+`,
+		"api/tools/go.mod": "module example.com/svc/tools\n\ngo 1.22\n",
+		"api/tools/gen.go": `package tools
+
+// @RestOperation( method = "GET", path = "/tools" )
+func (s *Tools) List() {}
+
+type Tools struct{}
+`,
+		"api/testdata/skip.go": `package skip
+
+// @RestOperation( method = "GET", path = "/skipped" )
+func (s *Skip) List() {}
+`,
+	})
+
+	t.Run("parses directory and records source and import path", func(t *testing.T) {
+		routes, err := ParseRouteMetadataDir(filepath.Join(root, "api"))
+		require.NoError(t, err)
+		require.Len(t, routes, 2)
+		assert.ElementsMatch(t, []string{"/orders", "/users"}, routePaths(routes))
+		for _, route := range routes {
+			assert.Equal(t, "example.com/svc/api", route.ImportPath)
+			assert.Equal(t, "api", route.Package)
+			assert.Contains(t, []string{filepath.Join(root, "api", "users.go"), filepath.Join(root, "api", "orders.go")}, route.SourceFile)
+		}
+	})
+
+	t.Run("parses glob", func(t *testing.T) {
+		routes, err := ParsePackages(filepath.Join(root, "api", "u*.go"))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"/users"}, routePaths(routes))
+	})
+
+	t.Run("parses package pattern recursively", func(t *testing.T) {
+		routes, err := ParsePackages(filepath.Join(root, "api") + "/...")
+		require.NoError(t, err)
+		// api/tools is a module of its own.
+		assert.Equal(t, []string{"/orders", "/users", "/v2/users"}, routePaths(routes))
+		for _, route := range routes {
+			if route.Operation.Path == "/v2/users" {
+				assert.Equal(t, "example.com/svc/api/v2", route.ImportPath)
+			}
+		}
+	})
+
+	t.Run("parses each file once", func(t *testing.T) {
+		routes, err := ParsePackages(filepath.Join(root, "api"), filepath.Join(root, "api", "users.go"))
+		require.NoError(t, err)
+		assert.Len(t, routes, 2)
+	})
+
+	t.Run("returns error for missing path", func(t *testing.T) {
+		routes, err := ParsePackages(filepath.Join(root, "missing"))
+		assert.Error(t, err)
+		assert.Nil(t, routes)
+	})
+}
+
+func TestRegisterPackageRoutes(t *testing.T) {
+	t.Run("registers only routes of the handler package", func(t *testing.T) {
+		ClearMiddlewares()
+		RegisterMiddleware("TestMiddleWare", testMiddleWare)
+		router := mux.NewRouter()
+		err := RegisterPackageRoutes(router, &Handler{}, "./route_register_test.go", "../example/person/handler.go")
+		require.NoError(t, err)
+		require.NotNil(t, router.Get("http.Handler.Get"))
+		assert.Nil(t, router.Get("person.Handler.GetPersonHTTP"))
+		req := httptest.NewRequest("GET", "/person/bill", nil)
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		assert.Equal(t, http.StatusOK, res.Code)
+	})
+}
+
+func createTempModule(t *testing.T, files map[string]string) string {
+	root := t.TempDir()
+	for name, content := range files {
+		file := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(file), 0755))
+		require.NoError(t, os.WriteFile(file, []byte(content), 0644))
+	}
+	return root
+}
+
+func routePaths(routes []*RouteMetadata) []string {
+	paths := make([]string, 0, len(routes))
+	for _, route := range routes {
+		paths = append(paths, route.Operation.Path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func TestParseRouteMetadataFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"api/users.go": {Data: []byte(`package api
+
+// @RestController( path = "/users" )
+type Users struct{}
+`)},
+		"api/users_routes.go": {Data: []byte(`package api
+
+// @RestOperation( method = "GET", path = "/{id}" )
+func (s *Users) Get() {}
+`)},
+		"api/users_test.go": {Data: []byte(`package api
+
+// @RestOperation( method = "GET", path = "/ignored" )
+func (s *Users) Ignored() {}
+`)},
+	}
+
+	t.Run("parses matching files together", func(t *testing.T) {
+		routes, err := ParseRouteMetadataFS(fsys, "api/*.go")
+		require.NoError(t, err)
+		require.Len(t, routes, 1)
+		assert.Equal(t, "/users/{id}", routes[0].Operation.Path)
+		assert.Equal(t, "api/users_routes.go", routes[0].SourceFile)
+		assert.Equal(t, 3, routes[0].Line)
+		assert.Empty(t, routes[0].ImportPath)
+	})
+
+	t.Run("returns error when nothing matches", func(t *testing.T) {
+		_, err := ParseRouteMetadataFS(fsys, "missing/*.go")
+		assert.ErrorContains(t, err, "no Go files match missing/*.go")
+	})
+
+	t.Run("registers routes from embedded sources", func(t *testing.T) {
+		registry := NewRegistry()
+		require.NoError(t, registry.RegisterMiddleware("TestMiddleWare", testMiddleWare))
+		router := mux.NewRouter()
+		sources := fstest.MapFS{"handler.go": {Data: mustReadFile(t, "route_register_test.go")}}
+		require.NoError(t, registry.RegisterRoutesFS(router, &Handler{}, sources, "handler.go"))
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, httptest.NewRequest("GET", "/person/bill", nil))
+		assert.Equal(t, http.StatusOK, res.Code)
+	})
+}
+
+func mustReadFile(t *testing.T, name string) []byte {
+	data, err := os.ReadFile(name)
+	require.NoError(t, err)
+	return data
+}
@@ -0,0 +1,125 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidPath is returned for paths whose variables cannot be registered.
+var ErrInvalidPath = errors.New("invalid path")
+
+// pathType is a type usable in annotation path variables, e.g. {id:int}.
+type pathType struct {
+	pattern string
+	convert func(string) (any, error)
+}
+
+// pathTypes lists the variable types of annotation paths. Values not matching the pattern
+// do not match the route (404), values failing conversion are rejected with 400.
+var pathTypes = map[string]pathType{
+	"int": {
+		pattern: `-?[0-9]+`,
+		convert: func(s string) (any, error) { return strconv.Atoi(s) },
+	},
+	"uuid": {
+		pattern: `[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`,
+	},
+}
+
+// pathParamSpec is a typed variable of an annotation path.
+type pathParamSpec struct {
+	name    string
+	typ     string
+	convert func(string) (any, error)
+}
+
+// compilePath translates the typed variables of an annotation path, {id:int}, {uid:uuid}
+// and {slug:regex(...)}, to the regular expressions understood by routers. Other variables
+// are kept as they are.
+func compilePath(path string) (string, []pathParamSpec, error) {
+	var b strings.Builder
+	var specs []pathParamSpec
+	for _, part := range splitTemplate(path) {
+		name, pattern, ok := pathVar(part)
+		if !ok {
+			if strings.ContainsAny(part, "{}") {
+				return "", nil, fmt.Errorf("%w %s: unbalanced braces", ErrInvalidPath, path)
+			}
+			b.WriteString(part)
+			continue
+		}
+		if name == "" {
+			return "", nil, fmt.Errorf("%w %s: variable without name", ErrInvalidPath, path)
+		}
+		if typ, ok := pathTypes[pattern]; ok {
+			specs = append(specs, pathParamSpec{name: name, typ: pattern, convert: typ.convert})
+			pattern = typ.pattern
+		} else if expr, ok := strings.CutPrefix(pattern, "regex("); ok {
+			if !strings.HasSuffix(expr, ")") {
+				return "", nil, fmt.Errorf("%w %s: unterminated regex for %s", ErrInvalidPath, path, name)
+			}
+			pattern = strings.TrimSuffix(expr, ")")
+			specs = append(specs, pathParamSpec{name: name, typ: "regex"})
+		}
+		if pattern != "" {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return "", nil, fmt.Errorf("%w %s: invalid pattern for %s: %v", ErrInvalidPath, path, name, err)
+			}
+			b.WriteString("{" + name + ":" + pattern + "}")
+		} else {
+			b.WriteString("{" + name + "}")
+		}
+	}
+	return b.String(), specs, nil
+}
+
+// routePattern returns the path registered on routers for an annotation path.
+func routePattern(path string) string {
+	compiled, _, err := compilePath(path)
+	if err != nil {
+		return path
+	}
+	return compiled
+}
+
+type pathValuesKey struct{}
+
+// convertPathParams converts the typed variables of the matched route before calling handler.
+// Conversion errors are answered with 400 Bad Request.
+func convertPathParams(handler http.HandlerFunc, specs []pathParamSpec) http.HandlerFunc {
+	hasConverters := false
+	for _, spec := range specs {
+		hasConverters = hasConverters || spec.convert != nil
+	}
+	if !hasConverters {
+		return handler
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		values := make(map[string]any, len(specs))
+		for _, spec := range specs {
+			if spec.convert == nil {
+				continue
+			}
+			value, err := spec.convert(PathParam(r, spec.name))
+			if err != nil {
+				writeError(w, &Error{Status: http.StatusBadRequest, Message: fmt.Sprintf("invalid path parameter %s", spec.name), Err: err})
+				return
+			}
+			values[spec.name] = value
+		}
+		handler(w, r.WithContext(context.WithValue(r.Context(), pathValuesKey{}, values)))
+	}
+}
+
+// PathInt returns the value of a path variable declared as {name:int}. ok is false when the
+// route has no such variable.
+func PathInt(r *http.Request, name string) (value int, ok bool) {
+	values, _ := r.Context().Value(pathValuesKey{}).(map[string]any)
+	value, ok = values[name].(int)
+	return value, ok
+}
@@ -0,0 +1,127 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type typedPathHandler struct{}
+
+// @RestOperation( method = "GET", path = "/orders/{id:int}", disableAuth = true )
+func (h *typedPathHandler) GetOrder(w http.ResponseWriter, r *http.Request) {
+	id, _ := PathInt(r, "id")
+	fmt.Fprintf(w, "order %d", id+1)
+}
+
+// @RestOperation( method = "GET", path = "/accounts/{uid:uuid}", disableAuth = true )
+func (h *typedPathHandler) GetAccount(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprintf(w, "account %s", PathParam(r, "uid"))
+}
+
+// @RestOperation( method = "GET", path = "/posts/{slug:regex([a-z0-9-]+)}", disableAuth = true )
+func (h *typedPathHandler) GetPost(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprintf(w, "post %s", PathParam(r, "slug"))
+}
+
+type orderRequest struct {
+	ID int `path:"id"`
+}
+
+type orderResponse struct {
+	ID int `json:"id"`
+}
+
+// @RestOperation( method = "PUT", path = "/orders/{id:int}", disableAuth = true )
+func (h *typedPathHandler) PutOrder(ctx context.Context, req *orderRequest) (*orderResponse, error) {
+	return &orderResponse{ID: req.ID}, nil
+}
+
+type securedPathHandler struct{}
+
+// @RestOperation( method = "GET", path = "/orders/{id:int}" )
+func (h *securedPathHandler) GetOrder(ctx context.Context, req *orderRequest) (*orderResponse, error) {
+	return &orderResponse{ID: req.ID}, nil
+}
+
+func TestCompilePath(t *testing.T) {
+	t.Run("translates typed variables", func(t *testing.T) {
+		path, specs, err := compilePath("/orders/{id:int}/items/{uid:uuid}/{slug:regex([a-z]{2,3})}/{name}")
+		require.NoError(t, err)
+		assert.Equal(t, "/orders/{id:-?[0-9]+}/items/{uid:"+pathTypes["uuid"].pattern+"}/{slug:[a-z]{2,3}}/{name}", path)
+		require.Len(t, specs, 3)
+		assert.Equal(t, "int", specs[0].typ)
+	})
+
+	t.Run("keeps plain regular expressions", func(t *testing.T) {
+		path, specs, err := compilePath("/orders/{id:[0-9]+}")
+		require.NoError(t, err)
+		assert.Equal(t, "/orders/{id:[0-9]+}", path)
+		assert.Empty(t, specs)
+	})
+
+	t.Run("rejects invalid paths when parsing annotations", func(t *testing.T) {
+		_, err := ParseRestOperation(`@RestOperation( method = "GET", path = "/posts/{slug:regex([a-z}" )`)
+		assert.ErrorIs(t, err, ErrInvalidPath)
+		_, err = ParseRestOperation(`@RestOperation( method = "GET", path = "/posts/{slug:regex(a)b}" )`)
+		assert.ErrorIs(t, err, ErrInvalidPath)
+	})
+}
+
+func TestTypedPathParams(t *testing.T) {
+	targets := map[string]func() (RouteTarget, http.Handler){
+		"mux": func() (RouteTarget, http.Handler) {
+			router := mux.NewRouter()
+			return MuxTarget(router), router
+		},
+		"servemux": func() (RouteTarget, http.Handler) {
+			serveMux := http.NewServeMux()
+			return ServeMuxTarget(serveMux), serveMux
+		},
+	}
+	for name, newTarget := range targets {
+		t.Run(name, func(t *testing.T) {
+			target, handler := newTarget()
+			require.NoError(t, NewRegistry().RegisterRoutesTo(target, &typedPathHandler{}, "./path_params_test.go"))
+
+			assert.Equal(t, "order 42", serve(handler, "/orders/41").Body.String())
+			assert.Equal(t, http.StatusNotFound, serve(handler, "/orders/abc").Code)
+			res := serve(handler, "/orders/99999999999999999999999")
+			assert.Equal(t, http.StatusBadRequest, res.Code)
+			assert.JSONEq(t, `{"error":"invalid path parameter id"}`, res.Body.String())
+
+			assert.Equal(t, "account 6f1c2a9e-0c3b-4d5e-8f70-123456789abc", serve(handler, "/accounts/6f1c2a9e-0c3b-4d5e-8f70-123456789abc").Body.String())
+			assert.Equal(t, http.StatusNotFound, serve(handler, "/accounts/bill").Code)
+
+			assert.Equal(t, "post hello-world", serve(handler, "/posts/hello-world").Body.String())
+			assert.Equal(t, http.StatusNotFound, serve(handler, "/posts/Hello").Code)
+		})
+	}
+
+	t.Run("binds typed values in typed handlers", func(t *testing.T) {
+		router := mux.NewRouter()
+		require.NoError(t, NewRegistry().RegisterRoutes(router, &typedPathHandler{}, "./path_params_test.go"))
+		req, _ := http.NewRequest("PUT", "/orders/7", nil)
+		res := serveRequest(router, req)
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.JSONEq(t, `{"id":7}`, res.Body.String())
+	})
+
+	t.Run("authenticates before converting", func(t *testing.T) {
+		registry := NewRegistry()
+		require.NoError(t, registry.RegisterAuthenticator(tokenAuthenticator{}))
+		router := mux.NewRouter()
+		require.NoError(t, registry.RegisterRoutes(router, &securedPathHandler{}, "./path_params_test.go"))
+		assert.Equal(t, http.StatusUnauthorized, serve(router, "/orders/99999999999999999999999").Code)
+
+		req := httptest.NewRequest("GET", "/orders/99999999999999999999999", nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		assert.Equal(t, http.StatusBadRequest, serveRequest(router, req).Code)
+	})
+}
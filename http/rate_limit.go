@@ -0,0 +1,357 @@
+package http
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimit is a token bucket limit declared with rateLimit = "100/s burst 20 by ip": Limit
+// requests are allowed per Period, with bursts of up to Burst requests. By selects the bucket
+// of a request: "ip", "principal" or "header:<name>".
+type RateLimit struct {
+	Limit  int
+	Period time.Duration
+	Burst  int
+	By     string
+}
+
+var ErrInvalidRateLimit = errors.New("invalid rate limit")
+
+var ratePeriods = map[string]time.Duration{"s": time.Second, "m": time.Minute, "h": time.Hour}
+
+// ParseRateLimit parses "<limit>/<s|m|h> [burst <n>] [by ip|principal|header:<name>]". Burst
+// defaults to the limit and requests are keyed by client IP unless by is given.
+func ParseRateLimit(s string) (*RateLimit, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("%w: empty", ErrInvalidRateLimit)
+	}
+	count, unit, ok := strings.Cut(fields[0], "/")
+	limit, err := strconv.Atoi(count)
+	if !ok || err != nil || limit <= 0 || ratePeriods[unit] == 0 {
+		return nil, fmt.Errorf("%w: %q must be <limit>/<s|m|h>, e.g. 100/s", ErrInvalidRateLimit, fields[0])
+	}
+	rl := &RateLimit{Limit: limit, Period: ratePeriods[unit], Burst: limit, By: "ip"}
+	for rest := fields[1:]; len(rest) > 0; rest = rest[2:] {
+		if len(rest) < 2 {
+			return nil, fmt.Errorf("%w: %s needs a value", ErrInvalidRateLimit, rest[0])
+		}
+		switch rest[0] {
+		case "burst":
+			rl.Burst, err = strconv.Atoi(rest[1])
+			if err != nil || rl.Burst <= 0 {
+				return nil, fmt.Errorf("%w: burst must be a positive integer", ErrInvalidRateLimit)
+			}
+		case "by":
+			rl.By = rest[1]
+			if name, ok := strings.CutPrefix(rl.By, "header:"); ok && name != "" {
+				continue
+			}
+			if rl.By != "ip" && rl.By != "principal" {
+				return nil, fmt.Errorf("%w: by must be ip, principal or header:<name>", ErrInvalidRateLimit)
+			}
+		default:
+			return nil, fmt.Errorf("%w: unknown option %s", ErrInvalidRateLimit, rest[0])
+		}
+	}
+	return rl, nil
+}
+
+// String formats the limit in annotation syntax.
+func (l RateLimit) String() string {
+	unit := "s"
+	for u, period := range ratePeriods {
+		if period == l.Period {
+			unit = u
+		}
+	}
+	return fmt.Sprintf("%d/%s burst %d by %s", l.Limit, unit, l.Burst, l.By)
+}
+
+// rate returns the number of tokens added per second.
+func (l RateLimit) rate() float64 {
+	return float64(l.Limit) / l.Period.Seconds()
+}
+
+// RateLimitResult is the outcome of taking a token from a bucket.
+type RateLimitResult struct {
+	Allowed   bool
+	Remaining int
+	// RetryAfter is the time until a token is available, when the request is not allowed.
+	RetryAfter time.Duration
+	// Reset is the time until the bucket is full again.
+	Reset time.Duration
+}
+
+// RateLimitStore keeps the token buckets of rate limited routes, e.g. in memory or in a store
+// shared by several instances.
+type RateLimitStore interface {
+	// Take removes a token from the bucket identified by key, refilled according to limit.
+	Take(ctx context.Context, key string, limit RateLimit, now time.Time) (RateLimitResult, error)
+}
+
+// DefaultMaxRateLimitBuckets is the number of buckets kept by NewMemoryRateLimitStore.
+const DefaultMaxRateLimitBuckets = 100_000
+
+// MemoryRateLimitStore is a RateLimitStore keeping buckets in memory. Buckets that are full
+// again are dropped periodically, and once the store holds its maximum number of buckets the
+// least recently used one is evicted, so clients making up keys, e.g. header values, cannot
+// grow it without bound. An evicted client starts again with a full bucket.
+type MemoryRateLimitStore struct {
+	mu         sync.Mutex
+	buckets    map[string]*list.Element
+	lru        list.List // of *tokenBucket, most recently used first
+	maxBuckets int
+	lastSweep  time.Time
+}
+
+type tokenBucket struct {
+	key    string
+	tokens float64
+	last   time.Time
+	full   time.Time
+}
+
+// NewMemoryRateLimitStore returns an empty in-memory store of DefaultMaxRateLimitBuckets buckets.
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return NewMemoryRateLimitStoreSize(DefaultMaxRateLimitBuckets)
+}
+
+// NewMemoryRateLimitStoreSize returns an empty in-memory store of at most maxBuckets buckets.
+func NewMemoryRateLimitStoreSize(maxBuckets int) *MemoryRateLimitStore {
+	if maxBuckets <= 0 {
+		maxBuckets = DefaultMaxRateLimitBuckets
+	}
+	return &MemoryRateLimitStore{buckets: make(map[string]*list.Element), maxBuckets: maxBuckets}
+}
+
+// Take implements RateLimitStore.
+func (s *MemoryRateLimitStore) Take(_ context.Context, key string, limit RateLimit, now time.Time) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now.Sub(s.lastSweep) > time.Minute {
+		for e := s.lru.Front(); e != nil; {
+			next := e.Next()
+			if b := e.Value.(*tokenBucket); !now.Before(b.full) {
+				s.remove(e)
+			}
+			e = next
+		}
+		s.lastSweep = now
+	}
+	rate, burst := limit.rate(), float64(limit.Burst)
+	var b *tokenBucket
+	if e, ok := s.buckets[key]; ok {
+		s.lru.MoveToFront(e)
+		b = e.Value.(*tokenBucket)
+	} else {
+		if s.lru.Len() >= s.maxBuckets {
+			s.remove(s.lru.Back())
+		}
+		b = &tokenBucket{key: key, tokens: burst, last: now}
+		s.buckets[key] = s.lru.PushFront(b)
+	}
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+	var result RateLimitResult
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - b.tokens) / rate)
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = seconds((burst - b.tokens) / rate)
+	b.full = now.Add(result.Reset)
+	return result, nil
+}
+
+// Len returns the number of buckets in the store.
+func (s *MemoryRateLimitStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lru.Len()
+}
+
+// remove drops the bucket of e. s.mu must be held.
+func (s *MemoryRateLimitStore) remove(e *list.Element) {
+	delete(s.buckets, s.lru.Remove(e).(*tokenBucket).key)
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// SetRateLimitStore sets the store of the token buckets of routes registered afterwards.
+// The default keeps them in memory. Pass nil to go back to a new in-memory store.
+func (reg *Registry) SetRateLimitStore(store RateLimitStore) {
+	if store == nil {
+		store = NewMemoryRateLimitStore()
+	}
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.rateLimitStore = store
+}
+
+// WithRateLimit wraps handler so requests beyond the rateLimit of route get 429 Too Many
+// Requests with a Retry-After header. Every response carries RateLimit-Limit,
+// RateLimit-Remaining and RateLimit-Reset headers. Routes without rateLimit are returned as is.
+func (reg *Registry) WithRateLimit(handler http.HandlerFunc, route *RouteMetadata) (http.HandlerFunc, error) {
+	op := route.Operation
+	if op.RateLimit == "" {
+		return handler, nil
+	}
+	limit, err := ParseRateLimit(op.RateLimit)
+	if err != nil {
+		return nil, err
+	}
+	if limit.By == "principal" && op.DisableAuth {
+		return nil, fmt.Errorf("%w: by principal requires authentication", ErrInvalidRateLimit)
+	}
+	reg.mu.RLock()
+	store, clientIP := reg.rateLimitStore, reg.clientIP
+	reg.mu.RUnlock()
+	if clientIP == nil {
+		clientIP = RemoteAddrIP
+	}
+	name := route.RouteName()
+	logger := reg.registrationLogger()
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := name + "|" + rateLimitKey(r, limit.By, clientIP)
+		result, err := store.Take(r.Context(), key, *limit, time.Now())
+		if err != nil {
+			// Fail open, the route stays available when the store does not.
+			logger.Error("rate limit store failed", "route", name, "error", err)
+			handler(w, r)
+			return
+		}
+		h := w.Header()
+		h.Set("RateLimit-Limit", strconv.Itoa(limit.Burst))
+		h.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+		h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d;burst=%d", limit.Limit, int(limit.Period.Seconds()), limit.Burst))
+		if !result.Allowed {
+			h.Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+			return
+		}
+		handler(w, r)
+	}, nil
+}
+
+// principalRateLimit reports whether op limits requests by principal.
+func principalRateLimit(op *RestOperation) bool {
+	limit, err := ParseRateLimit(op.RateLimit)
+	return err == nil && limit.By == "principal"
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// rateLimitKey identifies the client of r, falling back to its IP when the request has no
+// principal or header value.
+func rateLimitKey(r *http.Request, by string, clientIP ClientIPFunc) string {
+	switch {
+	case by == "principal":
+		if p, ok := PrincipalFromContext(r.Context()); ok {
+			return "principal:" + p.Name()
+		}
+	case strings.HasPrefix(by, "header:"):
+		if value := r.Header.Get(strings.TrimPrefix(by, "header:")); value != "" {
+			return by + ":" + value
+		}
+	}
+	return "ip:" + clientIP(r)
+}
+
+// ClientIPFunc returns the IP address of the client of r, which rate limits by ip key their buckets with.
+type ClientIPFunc func(r *http.Request) string
+
+// RemoteAddrIP is the default ClientIPFunc, the host of r.RemoteAddr. Behind a proxy or load
+// balancer that is the address of the proxy, use TrustedProxies there.
+func RemoteAddrIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// TrustedProxies returns a ClientIPFunc for servers behind the given proxies, IP addresses or
+// CIDR ranges such as "10.0.0.0/8". Requests from a trusted proxy are attributed to the last
+// address of their X-Forwarded-For header that is not a trusted proxy itself. Other requests,
+// whose header may be forged, are attributed to their RemoteAddr.
+func TrustedProxies(proxies ...string) (ClientIPFunc, error) {
+	var trusted []netip.Prefix
+	for _, proxy := range proxies {
+		prefix, err := netip.ParsePrefix(proxy)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(proxy)
+			if addrErr != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		trusted = append(trusted, prefix.Masked())
+	}
+	isTrusted := func(ip string) bool {
+		addr, err := netip.ParseAddr(ip)
+		if err != nil {
+			return false
+		}
+		addr = addr.Unmap()
+		for _, prefix := range trusted {
+			if prefix.Contains(addr) {
+				return true
+			}
+		}
+		return false
+	}
+	return func(r *http.Request) string {
+		ip := RemoteAddrIP(r)
+		if !isTrusted(ip) {
+			return ip
+		}
+		forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+		for i := len(forwarded) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(forwarded[i])
+			if hop == "" {
+				continue
+			}
+			ip = hop
+			if !isTrusted(hop) {
+				break
+			}
+		}
+		return ip
+	}, nil
+}
+
+// SetClientIP sets how rate limits by ip identify the client of the routes registered
+// afterwards. The default, RemoteAddrIP, uses the address of the connection.
+func (reg *Registry) SetClientIP(clientIP ClientIPFunc) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.clientIP = clientIP
+}
+
+// SetClientIP sets the client IP function of the default registry.
+func SetClientIP(clientIP ClientIPFunc) {
+	defaultRegistry.SetClientIP(clientIP)
+}
+
+// SetRateLimitStore sets the rate limit store of the default registry.
+func SetRateLimitStore(store RateLimitStore) {
+	defaultRegistry.SetRateLimitStore(store)
+}
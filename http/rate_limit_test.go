@@ -0,0 +1,245 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type limitedHandler struct{}
+
+// @RestOperation( method = "GET", path = "/search", disableAuth = true, rateLimit = "1/s burst 2 by ip" )
+func (h *limitedHandler) Search(w http.ResponseWriter, r *http.Request) {}
+
+// @RestOperation( method = "GET", path = "/export", disableAuth = true, rateLimit = "1/m by header:X-API-Key" )
+func (h *limitedHandler) Export(w http.ResponseWriter, r *http.Request) {}
+
+type securedLimitedHandler struct{}
+
+// @RestOperation( method = "POST", path = "/login", rateLimit = "1/m by ip" )
+func (h *securedLimitedHandler) Login(w http.ResponseWriter, r *http.Request) {}
+
+// @RestOperation( method = "GET", path = "/profile", rateLimit = "1/m by principal" )
+func (h *securedLimitedHandler) Profile(w http.ResponseWriter, r *http.Request) {}
+
+func TestParseRateLimit(t *testing.T) {
+	tests := []struct {
+		in   string
+		want RateLimit
+	}{
+		{"100/s", RateLimit{Limit: 100, Period: time.Second, Burst: 100, By: "ip"}},
+		{"100/s burst 20 by ip", RateLimit{Limit: 100, Period: time.Second, Burst: 20, By: "ip"}},
+		{"10/m by principal", RateLimit{Limit: 10, Period: time.Minute, Burst: 10, By: "principal"}},
+		{"5/h burst 1 by header:X-API-Key", RateLimit{Limit: 5, Period: time.Hour, Burst: 1, By: "header:X-API-Key"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			limit, err := ParseRateLimit(tt.in)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, *limit)
+		})
+	}
+
+	t.Run("rejects invalid limits", func(t *testing.T) {
+		for _, in := range []string{"", "100", "0/s", "100/d", "100/s burst", "100/s burst -1", "100/s by cookie", "100/s every 2"} {
+			_, err := ParseRateLimit(in)
+			assert.ErrorIs(t, err, ErrInvalidRateLimit, in)
+		}
+		_, err := ParseRestOperation(`@RestOperation( method = "GET", path = "/", rateLimit = "lots" )`)
+		assert.ErrorIs(t, err, ErrInvalidRateLimit)
+	})
+}
+
+func TestMemoryRateLimitStore(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	limit := RateLimit{Limit: 2, Period: time.Second, Burst: 2}
+	now := time.Now()
+
+	first, _ := store.Take(context.Background(), "a", limit, now)
+	second, _ := store.Take(context.Background(), "a", limit, now)
+	third, _ := store.Take(context.Background(), "a", limit, now)
+	assert.True(t, first.Allowed)
+	assert.Equal(t, 1, first.Remaining)
+	assert.True(t, second.Allowed)
+	assert.False(t, third.Allowed)
+	assert.Equal(t, 500*time.Millisecond, third.RetryAfter)
+	assert.Equal(t, time.Second, third.Reset)
+
+	refilled, _ := store.Take(context.Background(), "a", limit, now.Add(500*time.Millisecond))
+	assert.True(t, refilled.Allowed)
+	other, _ := store.Take(context.Background(), "b", limit, now)
+	assert.True(t, other.Allowed)
+}
+
+func TestMemoryRateLimitStoreEviction(t *testing.T) {
+	store := NewMemoryRateLimitStoreSize(2)
+	limit := RateLimit{Limit: 1, Period: time.Minute, Burst: 1}
+	now := time.Now()
+	take := func(key string) bool {
+		result, err := store.Take(context.Background(), key, limit, now)
+		require.NoError(t, err)
+		return result.Allowed
+	}
+
+	assert.True(t, take("a"))
+	assert.True(t, take("b"))
+	assert.False(t, take("a"))
+	for i := 0; i < 100; i++ {
+		take(fmt.Sprint("made-up-", i))
+	}
+	assert.Equal(t, 2, store.Len())
+	// The bucket of a was evicted, so a starts again with a full bucket.
+	assert.True(t, take("a"))
+}
+
+func TestTrustedProxies(t *testing.T) {
+	clientIP, err := TrustedProxies("10.0.0.0/8", "192.0.2.1")
+	require.NoError(t, err)
+	request := func(remoteAddr string, forwardedFor ...string) string {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = remoteAddr
+		for _, value := range forwardedFor {
+			req.Header.Add("X-Forwarded-For", value)
+		}
+		return clientIP(req)
+	}
+
+	assert.Equal(t, "203.0.113.7", request("10.1.2.3:4000", "203.0.113.7"))
+	assert.Equal(t, "203.0.113.7", request("192.0.2.1:4000", "198.51.100.1, 203.0.113.7", "10.0.0.2"))
+	assert.Equal(t, "198.51.100.9", request("198.51.100.9:4000", "203.0.113.7"), "untrusted peers cannot forge their address")
+	assert.Equal(t, "10.1.2.3", request("10.1.2.3:4000"))
+	assert.Equal(t, "198.51.100.9", RemoteAddrIP(&http.Request{RemoteAddr: "198.51.100.9:4000"}))
+
+	_, err = TrustedProxies("proxy.internal")
+	assert.ErrorContains(t, err, `invalid trusted proxy "proxy.internal"`)
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, RateLimit, time.Time) (RateLimitResult, error) {
+	return RateLimitResult{}, errors.New("store unavailable")
+}
+
+func TestRateLimit(t *testing.T) {
+	setup := func(t *testing.T) (*Registry, http.Handler) {
+		registry := NewRegistry()
+		router := mux.NewRouter()
+		require.NoError(t, registry.RegisterRoutes(router, &limitedHandler{}, "./rate_limit_test.go"))
+		return registry, router
+	}
+
+	t.Run("answers 429 once the burst is used", func(t *testing.T) {
+		_, router := setup(t)
+		assert.Equal(t, http.StatusOK, serve(router, "/search").Code)
+		res := serve(router, "/search")
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, "2", res.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, "0", res.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, "1;w=1;burst=2", res.Header().Get("RateLimit-Policy"))
+		res = serve(router, "/search")
+		assert.Equal(t, http.StatusTooManyRequests, res.Code)
+		assert.Equal(t, "1", res.Header().Get("Retry-After"))
+		assert.Equal(t, "2", res.Header().Get("RateLimit-Reset"))
+	})
+
+	t.Run("keys buckets by client", func(t *testing.T) {
+		_, router := setup(t)
+		request := func(key string) int {
+			req := httptest.NewRequest("GET", "/export", nil)
+			req.Header.Set("X-API-Key", key)
+			return serveRequest(router, req).Code
+		}
+		assert.Equal(t, http.StatusOK, request("alice"))
+		assert.Equal(t, http.StatusTooManyRequests, request("alice"))
+		assert.Equal(t, http.StatusOK, request("bob"))
+	})
+
+	t.Run("keys buckets by principal", func(t *testing.T) {
+		registry := NewRegistry()
+		handler := func(w http.ResponseWriter, r *http.Request) {}
+		limited, err := registry.WithRateLimit(handler, &RouteMetadata{Operation: &RestOperation{Method: "GET", Path: "/", RateLimit: "1/m by principal"}, HandlerMethod: "Get", Package: "api"})
+		require.NoError(t, err)
+		request := func(name string) int {
+			req := httptest.NewRequest("GET", "/", nil)
+			req = req.WithContext(ContextWithPrincipal(req.Context(), testPrincipal(name)))
+			return serveRequest(limited, req).Code
+		}
+		assert.Equal(t, http.StatusOK, request("alice"))
+		assert.Equal(t, http.StatusTooManyRequests, request("alice"))
+		assert.Equal(t, http.StatusOK, request("bob"))
+	})
+
+	t.Run("limits by ip before authentication and by principal after", func(t *testing.T) {
+		registry := NewRegistry()
+		require.NoError(t, registry.RegisterAuthenticator(tokenAuthenticator{}))
+		router := mux.NewRouter()
+		require.NoError(t, registry.RegisterRoutes(router, &securedLimitedHandler{}, "./rate_limit_test.go"))
+		login := func() int { return serveRequest(router, httptest.NewRequest("POST", "/login", nil)).Code }
+		assert.Equal(t, http.StatusUnauthorized, login())
+		assert.Equal(t, http.StatusTooManyRequests, login())
+
+		assert.Equal(t, http.StatusUnauthorized, serve(router, "/profile").Code)
+		assert.Equal(t, http.StatusUnauthorized, serve(router, "/profile").Code)
+		req := httptest.NewRequest("GET", "/profile", nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		assert.Equal(t, http.StatusOK, serveRequest(router, req).Code)
+	})
+
+	t.Run("requires authentication to key by principal", func(t *testing.T) {
+		_, err := NewRegistry().WithRateLimit(nil, &RouteMetadata{Operation: &RestOperation{Method: "GET", Path: "/", RateLimit: "1/m by principal", DisableAuth: true}})
+		assert.ErrorContains(t, err, "by principal requires authentication")
+	})
+
+	t.Run("fails open when the store fails", func(t *testing.T) {
+		registry := NewRegistry()
+		registry.SetRateLimitStore(failingStore{})
+		router := mux.NewRouter()
+		require.NoError(t, registry.RegisterRoutes(router, &limitedHandler{}, "./rate_limit_test.go"))
+		for i := 0; i < 3; i++ {
+			assert.Equal(t, http.StatusOK, serve(router, "/search").Code)
+		}
+	})
+
+	t.Run("uses the memory store when the store is reset to nil", func(t *testing.T) {
+		registry := NewRegistry()
+		registry.SetRateLimitStore(failingStore{})
+		registry.SetRateLimitStore(nil)
+		router := mux.NewRouter()
+		require.NoError(t, registry.RegisterRoutes(router, &limitedHandler{}, "./rate_limit_test.go"))
+		assert.Equal(t, http.StatusOK, serve(router, "/search").Code)
+		assert.Equal(t, http.StatusOK, serve(router, "/search").Code)
+		assert.Equal(t, http.StatusTooManyRequests, serve(router, "/search").Code)
+	})
+
+	t.Run("keys buckets by the client IP of trusted proxies", func(t *testing.T) {
+		registry := NewRegistry()
+		clientIP, err := TrustedProxies("192.0.2.0/24")
+		require.NoError(t, err)
+		registry.SetClientIP(clientIP)
+		router := mux.NewRouter()
+		require.NoError(t, registry.RegisterRoutes(router, &limitedHandler{}, "./rate_limit_test.go"))
+		request := func(client string) int {
+			req := httptest.NewRequest("GET", "/search", nil)
+			req.Header.Set("X-Forwarded-For", client)
+			return serveRequest(router, req).Code
+		}
+		// httptest requests come from 192.0.2.1.
+		assert.Equal(t, http.StatusOK, request("203.0.113.1"))
+		assert.Equal(t, http.StatusOK, request("203.0.113.1"))
+		assert.Equal(t, http.StatusTooManyRequests, request("203.0.113.1"))
+		assert.Equal(t, http.StatusOK, request("203.0.113.2"))
+	})
+
+	t.Run("lists limits in the route table", func(t *testing.T) {
+		registry, _ := setup(t)
+		assert.Equal(t, "1/s burst 2 by ip", registry.Routes()[0].RateLimit)
+	})
+}
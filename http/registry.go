@@ -0,0 +1,57 @@
+package http
+
+import (
+	"log/slog"
+	"net/http"
+	"sync"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Registry owns the middlewares, authenticator and route options used when registering
+// routes. Registries are independent of each other, so several servers or parallel tests
+// can live in one process. The package-level functions operate on a default registry.
+type Registry struct {
+	mu                    sync.RWMutex
+	middlewares           map[string]MiddlewareFunc
+	factories             map[string]MiddlewareFactory
+	authenticator         Authenticator
+	requireAuthenticator  bool
+	timeoutStatus         int
+	timeoutBody           string
+	timeoutObserver       TimeoutObserver
+	logger                *slog.Logger
+	accessLogger          *slog.Logger
+	accessLog             bool
+	metrics               *routeMetrics
+	tracerProvider        trace.TracerProvider
+	propagator            propagation.TextMapPropagator
+	middlewareSpans       bool
+	rateLimitStore        RateLimitStore
+	clientIP              ClientIPFunc
+	preflights            map[preflightKey]*preflight
+	etagResolver          ETagResolver
+	maxCachedResponseSize int
+	routes                []RouteInfo
+}
+
+var defaultRegistry = NewRegistry()
+
+// NewRegistry returns an empty registry with the default route options.
+func NewRegistry() *Registry {
+	return &Registry{
+		middlewares:          make(map[string]MiddlewareFunc),
+		factories:            make(map[string]MiddlewareFactory),
+		requireAuthenticator: true,
+		timeoutStatus:        http.StatusServiceUnavailable,
+		timeoutBody:          "request timed out",
+		metrics:              &routeMetrics{routes: make(map[metricsKey]*routeStats)},
+		rateLimitStore:       NewMemoryRateLimitStore(),
+	}
+}
+
+// DefaultRegistry returns the registry used by the package-level functions.
+func DefaultRegistry() *Registry {
+	return defaultRegistry
+}
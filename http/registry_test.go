@@ -0,0 +1,61 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Run("registries do not share middlewares", func(t *testing.T) {
+		t.Parallel()
+		first := NewRegistry()
+		second := NewRegistry()
+		mw := func(next http.HandlerFunc) http.HandlerFunc { return next }
+		require.NoError(t, first.RegisterMiddleware("TestMiddleWare", mw))
+		_, err := second.GetMiddleware("TestMiddleWare")
+		assert.Error(t, err)
+		require.NoError(t, second.RegisterMiddleware("TestMiddleWare", mw))
+	})
+
+	t.Run("registries do not share authenticators", func(t *testing.T) {
+		t.Parallel()
+		first := NewRegistry()
+		second := NewRegistry()
+		require.NoError(t, first.RegisterAuthenticator(tokenAuthenticator{}))
+		_, err := second.GetAuthenticator()
+		assert.ErrorIs(t, err, ErrNoAuthenticator)
+	})
+
+	t.Run("registers routes with its own middlewares", func(t *testing.T) {
+		t.Parallel()
+		for _, value := range []string{"first", "second"} {
+			registry := NewRegistry()
+			require.NoError(t, registry.RegisterMiddleware("TestMiddleWare", func(next http.HandlerFunc) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Set("X-Registry", value)
+					next(w, r)
+				}
+			}))
+			router := mux.NewRouter()
+			require.NoError(t, registry.RegisterRoutes(router, &Handler{}, "./route_register_test.go"))
+			req := httptest.NewRequest("GET", "/person/bill", nil)
+			res := httptest.NewRecorder()
+			router.ServeHTTP(res, req)
+			assert.Equal(t, http.StatusOK, res.Code)
+			assert.Equal(t, value, res.Header().Get("X-Registry"))
+		}
+	})
+
+	t.Run("uses its own timeout response", func(t *testing.T) {
+		t.Parallel()
+		registry := NewRegistry()
+		require.NoError(t, registry.SetTimeoutResponse(http.StatusGatewayTimeout, "slow"))
+		assert.Equal(t, http.StatusServiceUnavailable, DefaultRegistry().timeoutStatus)
+		assert.Equal(t, http.StatusGatewayTimeout, registry.timeoutStatus)
+	})
+}
@@ -3,61 +3,57 @@ package http
 import (
 	"errors"
 	"fmt"
-	"regexp"
-	"strconv"
-	"strings"
+	"time"
 )
 
 type RestOperation struct {
-	Method      string
-	Path        string
-	Middlewares []string
-	Timeout     int
-	DisableAuth bool
+	Method      string   `json:"method"`
+	Path        string   `json:"path"`
+	Middlewares []string `json:"middlewares,omitempty"`
+	Timeout     int      `json:"timeout"`
+	DisableAuth bool     `json:"disableAuth,omitempty"`
+	Order       int      `json:"order,omitempty"`
+	Queries     []string `json:"queries,omitempty"`
+	Headers     []string `json:"headers,omitempty"`
+	Host        string   `json:"host,omitempty"`
+	Schemes     []string `json:"schemes,omitempty"`
+
+	AccessLog        bool `json:"accessLog,omitempty"`
+	DisableAccessLog bool `json:"disableAccessLog,omitempty"`
+
+	DisableMetrics bool      `json:"disableMetrics,omitempty"`
+	MetricsBuckets []float64 `json:"metricsBuckets,omitempty"`
+
+	RateLimit string `json:"rateLimit,omitempty"`
+
+	CORS *CORS `json:"cors,omitempty"`
+
+	Cache string `json:"cache,omitempty"`
+	ETag  bool   `json:"etag,omitempty"`
 }
 
 var (
-	ErrInvalidFormat    = errors.New("invalid @RestOperation format")
-	ErrMissingMethod    = errors.New("method is required")
-	ErrMissingPath      = errors.New("path is required")
-	ErrInvalidMethod    = errors.New("invalid HTTP method")
-	ErrInvalidTimeout   = errors.New("timeout must be positive")
-	validMethods        = map[string]bool{"GET": true, "POST": true, "PUT": true, "DELETE": true, "PATCH": true}
-	annotationRegex     = regexp.MustCompile(`@RestOperation\s*\((.*)\)`)
-	paramRegex          = regexp.MustCompile(`(\w+)\s*=\s*([^,]+)`)
+	ErrInvalidFormat  = errors.New("invalid @RestOperation format")
+	ErrUnknownKey     = errors.New("unknown @RestOperation parameter")
+	ErrMissingMethod  = errors.New("method is required")
+	ErrMissingPath    = errors.New("path is required")
+	ErrInvalidMethod  = errors.New("invalid HTTP method")
+	ErrInvalidTimeout = errors.New("timeout must be positive")
+	ErrInvalidCache   = errors.New("cache only applies to GET operations")
+	ErrInvalidETag    = errors.New("etag only applies to GET, PUT, PATCH and DELETE operations")
+	validMethods      = map[string]bool{"GET": true, "POST": true, "PUT": true, "DELETE": true, "PATCH": true, "OPTIONS": true}
 )
 
 // ParseRestOperation parses a @RestOperation annotation string into a RestOperation struct.
+// Syntax errors are reported as *AnnotationError with the line and column inside annotation.
 func ParseRestOperation(annotation string) (*RestOperation, error) {
-	matches := annotationRegex.FindStringSubmatch(annotation)
-	if len(matches) < 2 {
-		return nil, ErrInvalidFormat
-	}
-	paramsStr := matches[1]
-	op := &RestOperation{Timeout: 30}
-	paramMatches := paramRegex.FindAllStringSubmatch(paramsStr, -1)
-	for _, match := range paramMatches {
-		if len(match) < 3 {
-			continue
-		}
-		key := strings.TrimSpace(match[1])
-		value := strings.TrimSpace(match[2])
-		switch key {
-		case "method":
-			op.Method = strings.Trim(value, `"`)
-		case "path":
-			op.Path = strings.Trim(value, `"`)
-		case "middlewares":
-			op.Middlewares = parseArray(value)
-		case "timeout":
-			t, err := strconv.Atoi(value)
-			if err != nil {
-				return nil, fmt.Errorf("invalid timeout value: %w", err)
-			}
-			op.Timeout = t
-		case "disableAuth":
-			op.DisableAuth = value == "true"
-		}
+	ann, err := parseAnnotation(annotation, "RestOperation")
+	if err != nil {
+		return nil, err
+	}
+	op, err := newRestOperation(ann)
+	if err != nil {
+		return nil, err
 	}
 	if err := op.Validate(); err != nil {
 		return nil, err
@@ -65,6 +61,97 @@ func ParseRestOperation(annotation string) (*RestOperation, error) {
 	return op, nil
 }
 
+func newRestOperation(ann *annotation) (*RestOperation, error) {
+	op := &RestOperation{}
+	for _, arg := range ann.args {
+		if err := op.set(arg); err != nil {
+			if errors.Is(err, ErrUnknownKey) {
+				return nil, ann.errorAt(arg.offset, err, "unknown parameter %s", arg.key)
+			}
+			return nil, ann.errorAt(arg.value.offset, err, "%v", err)
+		}
+	}
+	return op, nil
+}
+
+func (r *RestOperation) set(arg *annotationArg) error {
+	var err error
+	switch arg.key {
+	case "method":
+		r.Method, err = arg.value.asString()
+	case "path":
+		r.Path, err = arg.value.asString()
+	case "middlewares":
+		r.Middlewares, err = arg.value.asStrings()
+		if err == nil {
+			err = validateMiddlewareRefs(r.Middlewares)
+		}
+	case "timeout":
+		r.Timeout, err = parseTimeout(arg.value)
+	case "disableAuth":
+		r.DisableAuth, err = arg.value.asBool()
+	case "order":
+		r.Order, err = arg.value.asInt()
+	case "queries":
+		r.Queries, err = arg.value.asStrings()
+		if err == nil {
+			err = validateMatchPairs(r.Queries)
+		}
+	case "headers":
+		r.Headers, err = arg.value.asStrings()
+		if err == nil {
+			err = validateMatchPairs(r.Headers)
+		}
+	case "host":
+		r.Host, err = arg.value.asString()
+	case "schemes":
+		r.Schemes, err = arg.value.asStrings()
+		if err == nil {
+			err = validateSchemes(r.Schemes)
+		}
+	case "accessLog":
+		r.AccessLog, err = arg.value.asBool()
+	case "disableAccessLog":
+		r.DisableAccessLog, err = arg.value.asBool()
+	case "disableMetrics":
+		r.DisableMetrics, err = arg.value.asBool()
+	case "metricsBuckets":
+		r.MetricsBuckets, err = parseBuckets(arg.value)
+	case "rateLimit":
+		r.RateLimit, err = arg.value.asString()
+		if err == nil {
+			_, err = ParseRateLimit(r.RateLimit)
+		}
+	case "cors":
+		r.CORS, err = parseCORS(arg.value)
+	case "cache":
+		r.Cache, err = arg.value.asString()
+	case "etag":
+		r.ETag, err = arg.value.asBool()
+	default:
+		return ErrUnknownKey
+	}
+	if err != nil {
+		return fmt.Errorf("invalid %s value: %w", arg.key, err)
+	}
+	return nil
+}
+
+// parseTimeout accepts a number of seconds or a duration with whole seconds, e.g. 30 or 1m30s.
+func parseTimeout(v *annotationValue) (int, error) {
+	if v.kind == numberValue {
+		return v.asInt()
+	}
+	d, err := v.asDuration()
+	if err != nil {
+		return 0, err
+	}
+	if d%time.Second != 0 {
+		return 0, fmt.Errorf("%s is not a whole number of seconds", d)
+	}
+	return int(d / time.Second), nil
+}
+
 // Validate checks if the RestOperation has valid values.
 func (r *RestOperation) Validate() error {
 	if r.Method == "" {
@@ -76,25 +163,52 @@ func (r *RestOperation) Validate() error {
 	if r.Path == "" {
 		return ErrMissingPath
 	}
+	if _, _, err := compilePath(r.Path); err != nil {
+		return err
+	}
 	if r.Timeout < 0 {
 		return ErrInvalidTimeout
 	}
-	return nil
+	if r.Cache != "" && r.Method != "GET" {
+		return ErrInvalidCache
+	}
+	if r.ETag && (r.Method == "POST" || r.Method == "OPTIONS") {
+		return ErrInvalidETag
+	}
+	return r.validateValues()
 }
 
-func parseArray(s string) []string {
-	s = strings.Trim(s, "[]")
-	if s == "" {
+// validateValues repeats the checks set makes on parameter values, for operations that were
+// not parsed from an annotation, e.g. decoded from a manifest.
+func (r *RestOperation) validateValues() error {
+	check := func(key string, err error) error {
+		if err != nil {
+			return fmt.Errorf("invalid %s value: %w", key, err)
+		}
 		return nil
 	}
-	parts := strings.Split(s, " ")
-	result := make([]string, 0, len(parts))
-	for _, part := range parts {
-		part = strings.TrimSpace(part)
-		part = strings.Trim(part, `"`)
-		if part != "" {
-			result = append(result, part)
+	if err := check("middlewares", validateMiddlewareRefs(r.Middlewares)); err != nil {
+		return err
+	}
+	if err := check("queries", validateMatchPairs(r.Queries)); err != nil {
+		return err
+	}
+	if err := check("headers", validateMatchPairs(r.Headers)); err != nil {
+		return err
+	}
+	if err := check("schemes", validateSchemes(r.Schemes)); err != nil {
+		return err
+	}
+	if err := check("metricsBuckets", validateBuckets(r.MetricsBuckets)); err != nil {
+		return err
+	}
+	if r.RateLimit != "" {
+		if _, err := ParseRateLimit(r.RateLimit); err != nil {
+			return check("rateLimit", err)
 		}
 	}
-	return result
+	if r.CORS != nil {
+		return check("cors", r.CORS.validate())
+	}
+	return nil
 }
@@ -26,7 +26,7 @@ func TestParseRestOperation(t *testing.T) {
 		assert.Equal(t, "POST", op.Method)
 		assert.Equal(t, "/users", op.Path)
 		assert.Nil(t, op.Middlewares)
-		assert.Equal(t, 30, op.Timeout)
+		assert.Zero(t, op.Timeout)
 		assert.False(t, op.DisableAuth)
 	})
 
@@ -170,30 +170,3 @@ func TestValidate(t *testing.T) {
 		assert.NoError(t, err)
 	})
 }
-
-func TestParseArray(t *testing.T) {
-	t.Run("parses array with multiple elements", func(t *testing.T) {
-		result := parseArray(`["auth" "logging" "metrics"]`)
-		assert.Equal(t, []string{"auth", "logging", "metrics"}, result)
-	})
-
-	t.Run("parses array with single element", func(t *testing.T) {
-		result := parseArray(`["auth"]`)
-		assert.Equal(t, []string{"auth"}, result)
-	})
-
-	t.Run("parses empty array", func(t *testing.T) {
-		result := parseArray(`[]`)
-		assert.Nil(t, result)
-	})
-
-	t.Run("handles whitespace", func(t *testing.T) {
-		result := parseArray(`[ "auth"  "logging" ]`)
-		assert.Equal(t, []string{"auth", "logging"}, result)
-	})
-
-	t.Run("handles empty string", func(t *testing.T) {
-		result := parseArray(``)
-		assert.Nil(t, result)
-	})
-}
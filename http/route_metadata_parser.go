@@ -1,55 +1,201 @@
 package http
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"go/ast"
 	"go/parser"
+	"go/printer"
 	"go/token"
 	"strings"
 )
 
 type RouteMetadata struct {
-	Operation     *RestOperation
-	HandlerMethod string
-	HandlerType   string
-	Package       string
+	Operation     *RestOperation `json:"operation"`
+	HandlerMethod string         `json:"handlerMethod"`
+	HandlerType   string         `json:"handlerType,omitempty"`
+	Package       string         `json:"package"`
+	SourceFile    string         `json:"sourceFile"`
+	Line          int            `json:"line,omitempty"`
+	ImportPath    string         `json:"importPath,omitempty"`
+	Doc           string         `json:"doc,omitempty"`
+	Signature     string         `json:"signature,omitempty"`
+	// ValueReceiver is set for methods declared on a value rather than a pointer receiver.
+	ValueReceiver bool `json:"valueReceiver,omitempty"`
+}
+
+// IsTyped reports whether the handler signature is func(context.Context, *T) (*R, error)
+// rather than an http.HandlerFunc. Whether T is a struct cannot be told from the source,
+// TypedHandler rejects other types at request time.
+func (m *RouteMetadata) IsTyped() bool {
+	params, results, ok := signatureTypes(m.Signature)
+	if !ok || len(params) != 2 || len(results) != 2 {
+		return false
+	}
+	ctx, ok := params[0].(*ast.SelectorExpr)
+	if !ok || ctx.Sel.Name != "Context" {
+		return false
+	}
+	_, reqPtr := params[1].(*ast.StarExpr)
+	_, resPtr := results[0].(*ast.StarExpr)
+	errType, ok := results[1].(*ast.Ident)
+	return reqPtr && resPtr && ok && errType.Name == "error"
+}
+
+// isHandlerFunc reports whether the handler signature has the shape of an http.HandlerFunc,
+// two parameters and no results.
+func (m *RouteMetadata) isHandlerFunc() bool {
+	params, results, ok := signatureTypes(m.Signature)
+	return ok && len(params) == 2 && len(results) == 0
+}
+
+// signatureTypes returns the parameter and result types of a function signature, one per
+// parameter even when names share a type.
+func signatureTypes(signature string) (params, results []ast.Expr, ok bool) {
+	expr, err := parser.ParseExpr(signature)
+	if err != nil {
+		return nil, nil, false
+	}
+	fn, ok := expr.(*ast.FuncType)
+	if !ok {
+		return nil, nil, false
+	}
+	return fieldTypes(fn.Params), fieldTypes(fn.Results), true
+}
+
+func fieldTypes(fields *ast.FieldList) []ast.Expr {
+	if fields == nil {
+		return nil
+	}
+	var types []ast.Expr
+	for _, field := range fields.List {
+		for range max(len(field.Names), 1) {
+			types = append(types, field.Type)
+		}
+	}
+	return types
+}
+
+// RouteName returns the name the route is registered under, e.g. "person.Handler.GetPersonHTTP",
+// or "person.GetPerson" for a top-level function.
+func (m *RouteMetadata) RouteName() string {
+	if m.HandlerType == "" {
+		return m.Package + "." + m.HandlerMethod
+	}
+	return fmt.Sprintf("%s.%s.%s", m.Package, m.HandlerType, m.HandlerMethod)
+}
+
+// Position returns the source position of the route annotation, e.g. "person/handler.go:42".
+func (m *RouteMetadata) Position() string {
+	if m.Line == 0 {
+		return m.SourceFile
+	}
+	return fmt.Sprintf("%s:%d", m.SourceFile, m.Line)
+}
+
+// HandlerName returns the qualified handler, e.g. "example.com/svc/person.(*Handler).GetPerson",
+// or "example.com/svc/person.Handler.GetPerson" for a value receiver.
+func (m *RouteMetadata) HandlerName() string {
+	pkg := m.ImportPath
+	if pkg == "" {
+		pkg = m.Package
+	}
+	if m.HandlerType == "" {
+		return pkg + "." + m.HandlerMethod
+	}
+	if m.ValueReceiver {
+		return fmt.Sprintf("%s.%s.%s", pkg, m.HandlerType, m.HandlerMethod)
+	}
+	return fmt.Sprintf("%s.(*%s).%s", pkg, m.HandlerType, m.HandlerMethod)
 }
 
 // ParseRouteMetadata parses a Go source file and extracts route metadata from @RestOperation annotations.
 func ParseRouteMetadata(filePath string) ([]*RouteMetadata, error) {
+	return parseRouteFiles([]string{filePath}, nil)
+}
+
+// parseRouteFiles parses the files of a single package together, so a @RestController
+// declared in one file applies to handler methods in the others. Sources are read with
+// readFile, or from disk when it is nil.
+func parseRouteFiles(filePaths []string, readFile func(string) ([]byte, error)) ([]*RouteMetadata, error) {
 	fset := token.NewFileSet()
-	file, err := parser.ParseFile(fset, filePath, nil, parser.ParseComments)
+	files := make([]*ast.File, 0, len(filePaths))
+	for _, filePath := range filePaths {
+		var src any
+		if readFile != nil {
+			data, err := readFile(filePath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse file: %w", err)
+			}
+			src = data
+		}
+		file, err := parser.ParseFile(fset, filePath, src, parser.ParseComments)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse file: %w", err)
+		}
+		files = append(files, file)
+	}
+	controllers, err := parseControllers(fset, files)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse file: %w", err)
+		return nil, err
 	}
 	var routes []*RouteMetadata
-	for _, decl := range file.Decls {
-		fn, ok := decl.(*ast.FuncDecl)
-		if !ok || fn.Doc == nil {
-			continue
-		}
-		for _, comment := range fn.Doc.List {
-			if !strings.Contains(comment.Text, "@RestOperation") {
+	for i, file := range files {
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Doc == nil {
 				continue
 			}
-			op, err := ParseRestOperation(comment.Text)
-			if err != nil {
-				return nil, fmt.Errorf("failed to parse annotation on %s: %w", fn.Name.Name, err)
-			}
-			metadata := &RouteMetadata{
-				Operation:     op,
-				HandlerMethod: fn.Name.Name,
-				Package:       file.Name.Name,
+			for _, comment := range fn.Doc.List {
+				if !strings.Contains(comment.Text, "@RestOperation") {
+					continue
+				}
+				metadata := &RouteMetadata{
+					HandlerMethod: fn.Name.Name,
+					Package:       file.Name.Name,
+					SourceFile:    filePaths[i],
+					Line:          fset.Position(comment.Slash).Line,
+					Doc:           docText(fn.Doc),
+					Signature:     nodeString(fset, fn.Type),
+				}
+				if fn.Recv != nil && len(fn.Recv.List) > 0 {
+					metadata.HandlerType = extractReceiverType(fn.Recv.List[0])
+					_, metadata.ValueReceiver = fn.Recv.List[0].Type.(*ast.Ident)
+				}
+				op, err := parseOperation(comment.Text, controllers[metadata.HandlerType])
+				if err != nil {
+					return nil, fmt.Errorf("failed to parse annotation on %s: %w", fn.Name.Name, positionError(fset, comment, err))
+				}
+				metadata.Operation = op
+				routes = append(routes, metadata)
 			}
-			if fn.Recv != nil && len(fn.Recv.List) > 0 {
-				metadata.HandlerType = extractReceiverType(fn.Recv.List[0])
-			}
-			routes = append(routes, metadata)
 		}
 	}
 	return routes, nil
 }
 
+// parseOperation parses a @RestOperation annotation and merges the defaults of its
+// controller, if any, before validating it.
+func parseOperation(text string, ctrl *RestController) (*RestOperation, error) {
+	if ctrl == nil {
+		return ParseRestOperation(text)
+	}
+	ann, err := parseAnnotation(text, "RestOperation")
+	if err != nil {
+		return nil, err
+	}
+	op, err := newRestOperation(ann)
+	if err != nil {
+		return nil, err
+	}
+	ctrl.apply(op, ann.has)
+	if err := op.Validate(); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
 func extractReceiverType(field *ast.Field) string {
 	switch t := field.Type.(type) {
 	case *ast.StarExpr:
@@ -61,3 +207,30 @@ func extractReceiverType(field *ast.Field) string {
 	}
 	return ""
 }
+
+func nodeString(fset *token.FileSet, node ast.Node) string {
+	var buf bytes.Buffer
+	printer.Fprint(&buf, fset, node)
+	return buf.String()
+}
+
+// docText returns the doc comment of a handler without its annotation lines.
+func docText(doc *ast.CommentGroup) string {
+	filtered := &ast.CommentGroup{}
+	for _, comment := range doc.List {
+		if !strings.Contains(comment.Text, "@RestOperation") {
+			filtered.List = append(filtered.List, comment)
+		}
+	}
+	return strings.TrimSpace(filtered.Text())
+}
+
+// positionError attaches the file position of the failing annotation to err.
+func positionError(fset *token.FileSet, comment *ast.Comment, err error) *AnnotationError {
+	var annErr *AnnotationError
+	if !errors.As(err, &annErr) {
+		annErr = &AnnotationError{Msg: err.Error(), Err: err}
+	}
+	annErr.Pos = fset.Position(comment.Slash + token.Pos(annErr.Offset))
+	return annErr
+}
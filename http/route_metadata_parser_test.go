@@ -196,6 +196,23 @@ type Service struct{}
 	})
 }
 
+func TestIsTyped(t *testing.T) {
+	for signature, typed := range map[string]bool{
+		"func(ctx context.Context, req *Request) (*Response, error)": true,
+		"func(context.Context, *Request) (*Response, error)":         true,
+		"func(w http.ResponseWriter, r *http.Request)":               false,
+		"func(ctx context.Context, req Request) (*Response, error)":  false,
+		"func(ctx context.Context, req *Request) (Response, error)":  false,
+		"func(a, b *Request) (*Response, error)":                     false,
+		"func(ctx context.Context, req *Request) (*Response, bool)":  false,
+		"": false,
+	} {
+		t.Run(signature, func(t *testing.T) {
+			assert.Equal(t, typed, (&RouteMetadata{Signature: signature}).IsTyped())
+		})
+	}
+}
+
 func createTempFile(t *testing.T, content string) string {
 	tmpDir := t.TempDir()
 	tmpFile := filepath.Join(tmpDir, "test.go")
@@ -3,53 +3,367 @@ package http
 import (
 	"errors"
 	"fmt"
-	"log"
+	"io/fs"
 	"net/http"
 	"reflect"
+	"time"
 
 	"github.com/gorilla/mux"
 )
 
 // RegisterRoutes parses annotations from handlerFile and registers routes on the router using reflection.
-func RegisterRoutes(router *mux.Router, handler any, handlerFile string) error {
+// Only methods of handler are registered, use RegisterFuncRoutes for top-level functions.
+func (reg *Registry) RegisterRoutes(router *mux.Router, handler any, handlerFile string) error {
+	return reg.RegisterRoutesTo(MuxTarget(router), handler, handlerFile)
+}
+
+// RegisterRoutesTo is RegisterRoutes for any RouteTarget, e.g. ServeMuxTarget or ChiTarget.
+func (reg *Registry) RegisterRoutesTo(target RouteTarget, handler any, handlerFile string) error {
+	routes, err := ParseRouteMetadata(handlerFile)
+	if err != nil {
+		return fmt.Errorf("failed to parse routes: %w", err)
+	}
+	return reg.registerHandlerRoutes(target, handler, routes)
+}
+
+// RegisterRoutesFS parses annotations from the files of fsys matching pattern (see ParseRouteMetadataFS)
+// and registers the routes implemented by handler on the router.
+func (reg *Registry) RegisterRoutesFS(router *mux.Router, handler any, fsys fs.FS, pattern string) error {
+	return reg.RegisterRoutesFSTo(MuxTarget(router), handler, fsys, pattern)
+}
+
+// RegisterRoutesFSTo is RegisterRoutesFS for any RouteTarget.
+func (reg *Registry) RegisterRoutesFSTo(target RouteTarget, handler any, fsys fs.FS, pattern string) error {
+	routes, err := ParseRouteMetadataFS(fsys, pattern)
+	if err != nil {
+		return fmt.Errorf("failed to parse routes: %w", err)
+	}
+	return reg.registerHandlerRoutes(target, handler, routes)
+}
+
+// RegisterPackageRoutes parses annotations from every file matched by patterns (see ParsePackages)
+// and registers the routes implemented by handler on the router. Routes declared in another
+// package than the handler's are skipped even when the type names match.
+func (reg *Registry) RegisterPackageRoutes(router *mux.Router, handler any, patterns ...string) error {
+	return reg.RegisterPackageRoutesTo(MuxTarget(router), handler, patterns...)
+}
+
+// RegisterPackageRoutesTo is RegisterPackageRoutes for any RouteTarget.
+func (reg *Registry) RegisterPackageRoutesTo(target RouteTarget, handler any, patterns ...string) error {
+	routes, err := ParsePackages(patterns...)
+	if err != nil {
+		return fmt.Errorf("failed to parse routes: %w", err)
+	}
+	return reg.registerHandlerRoutes(target, handler, routes)
+}
+
+func (reg *Registry) registerHandlerRoutes(target RouteTarget, handler any, routes []*RouteMetadata) error {
+	bound, errs, err := bindMethods(handler, routes)
+	if err != nil {
+		return err
+	}
+	return reg.registerBound(target, bound, errs)
+}
+
+// HandlerFuncs maps the names of annotated top-level functions to the functions themselves.
+// Values must be http.HandlerFunc compatible or typed handlers func(context.Context, *T) (*R, error).
+type HandlerFuncs map[string]any
+
+// RegisterFuncRoutes parses annotations from handlerFile and registers the routes declared on
+// top-level functions, looking each function up by name in funcs.
+func (reg *Registry) RegisterFuncRoutes(router *mux.Router, funcs HandlerFuncs, handlerFile string) error {
+	return reg.RegisterFuncRoutesTo(MuxTarget(router), funcs, handlerFile)
+}
+
+// RegisterFuncRoutesTo is RegisterFuncRoutes for any RouteTarget.
+func (reg *Registry) RegisterFuncRoutesTo(target RouteTarget, funcs HandlerFuncs, handlerFile string) error {
 	routes, err := ParseRouteMetadata(handlerFile)
 	if err != nil {
 		return fmt.Errorf("failed to parse routes: %w", err)
 	}
-	handlerValue := reflect.ValueOf(handler)
-	handlerType := reflect.TypeOf(handler)
-	if handlerType.Kind() != reflect.Ptr || handlerType.Elem().Kind() != reflect.Struct {
-		return fmt.Errorf("handler must be a pointer to struct")
+	return reg.registerFuncRoutes(target, funcs, routes)
+}
+
+// RegisterPackageFuncRoutes parses annotations from every file matched by patterns (see ParsePackages)
+// and registers the routes declared on top-level functions.
+func (reg *Registry) RegisterPackageFuncRoutes(router *mux.Router, funcs HandlerFuncs, patterns ...string) error {
+	return reg.RegisterPackageFuncRoutesTo(MuxTarget(router), funcs, patterns...)
+}
+
+// RegisterPackageFuncRoutesTo is RegisterPackageFuncRoutes for any RouteTarget.
+func (reg *Registry) RegisterPackageFuncRoutesTo(target RouteTarget, funcs HandlerFuncs, patterns ...string) error {
+	routes, err := ParsePackages(patterns...)
+	if err != nil {
+		return fmt.Errorf("failed to parse routes: %w", err)
 	}
-	handlerType = handlerType.Elem()
-	for _, route := range routes {
-		if route.HandlerType != "" && route.HandlerType != handlerType.Name() {
+	return reg.registerFuncRoutes(target, funcs, routes)
+}
+
+func (reg *Registry) registerFuncRoutes(target RouteTarget, funcs HandlerFuncs, routes []*RouteMetadata) error {
+	bound, errs := bindFuncs(funcs, routes)
+	return reg.registerBound(target, bound, errs)
+}
+
+// RegisterRoute registers a single annotated route on the router, wrapping handler with the
+// middlewares, authentication and timeout declared by its operation. It is the entry point
+// used by generated registration code, which needs no source files or reflection at runtime.
+func (reg *Registry) RegisterRoute(router *mux.Router, route *RouteMetadata, handler http.HandlerFunc) error {
+	return reg.RegisterRouteTo(MuxTarget(router), route, handler)
+}
+
+// RegisterRouteTo is RegisterRoute for any RouteTarget.
+func (reg *Registry) RegisterRouteTo(target RouteTarget, route *RouteMetadata, handler http.HandlerFunc) error {
+	return reg.registerBound(target, []boundRoute{{route, handler}}, nil)
+}
+
+// BoundRoute is an annotated route together with the handler implementing it.
+type BoundRoute struct {
+	Route   *RouteMetadata
+	Handler http.HandlerFunc
+}
+
+// RegisterBoundRoutesTo registers routes together on target, like RegisterRoutes: when one of them
+// fails, none is registered and every problem is returned. Generated registration code uses it.
+func (reg *Registry) RegisterBoundRoutesTo(target RouteTarget, routes ...BoundRoute) error {
+	bound := make([]boundRoute, len(routes))
+	for i, route := range routes {
+		bound[i] = boundRoute{route.Route, route.Handler}
+	}
+	return reg.registerBound(target, bound, nil)
+}
+
+// registerBound wraps every bound route, in the order given by their order key, and checks it
+// for conflicts with the routes of target and of bound itself, and on a scratch router when the
+// target has one. Routes are only added once all of them passed, otherwise the problems are
+// returned together as *RouteError values joined with errors.Join. Shadowed routes are logged
+// as warnings for targets matching routes in registration order. A nil target only validates.
+func (reg *Registry) registerBound(target RouteTarget, bound []boundRoute, errs []error) error {
+	var existing []registeredRoute
+	ordered, isOrdered := target.(orderedTarget)
+	if isOrdered {
+		existing = ordered.registeredRoutes()
+	}
+	sortByOrder(bound)
+	logger := reg.registrationLogger()
+	key := targetKey(target)
+	var validate func(method, path string, match RouteMatch) error
+	if validating, ok := target.(validatingTarget); ok {
+		validate = validating.validator()
+	}
+	var prepared []boundRoute
+	wrapped := make(map[*RouteMetadata]*wrappedRoute)
+	takeovers := make(map[*RouteMetadata]*preflight)
+	for _, b := range bound {
+		wr, err := reg.wrapRoute(b.route, b.handler)
+		if err != nil {
+			errs = append(errs, &RouteError{Route: b.route, Err: err})
 			continue
 		}
-		method := handlerValue.MethodByName(route.HandlerMethod)
-		if !method.IsValid() {
-			return fmt.Errorf("method %s not found in handler %s", route.HandlerMethod, route.HandlerType)
+		if _, ok := target.(MatchingTarget); target != nil && !ok && !b.route.Operation.Match().IsZero() {
+			errs = append(errs, &RouteError{Route: b.route, Err: errUnsupportedMatchers})
+			continue
 		}
-
-		httpHandler, err := createHTTPHandler(method)
+		takeover, err := reg.explicitPreflight(key, b.route)
 		if err != nil {
-			return fmt.Errorf("failed to create http handler for %s.%s : %w", route.HandlerType, route.HandlerMethod, err)
+			errs = append(errs, &RouteError{Route: b.route, Err: err})
+			continue
 		}
-
-		httpHandler, err = ApplyMiddlewares(httpHandler, *route.Operation)
+		others := existing
+		if takeover != nil {
+			others = withoutPreflights(existing, b.route)
+		} else if validate != nil {
+			op := b.route.Operation
+			if err := validate(op.Method, routePattern(op.Path), op.Match()); err != nil {
+				errs = append(errs, &RouteError{Route: b.route, Err: err})
+				continue
+			}
+		}
+		warnings, err := checkConflicts(others, b.route)
 		if err != nil {
-			return fmt.Errorf("failed to apply middlewares to handler: %w", err)
+			errs = append(errs, &RouteError{Route: b.route, Err: err})
+			continue
+		}
+		if isOrdered || target == nil {
+			for _, warning := range warnings {
+				logger.Warn("shadowed route", "error", &RouteError{Route: b.route, Err: warning})
+			}
+		}
+		existing = append(existing, registeredRoute{name: b.route.RouteName(), method: b.route.Operation.Method, path: routePattern(b.route.Operation.Path), match: b.route.Operation.Match().key()})
+		prepared = append(prepared, boundRoute{b.route, wr.handler})
+		wrapped[b.route] = wr
+		if takeover != nil {
+			takeovers[b.route] = takeover
+		}
+	}
+	preparedRoutes := make([]*RouteMetadata, len(prepared))
+	for i, p := range prepared {
+		preparedRoutes[i] = p.route
+	}
+	errs = append(errs, reg.validatePreflights(key, existing, preparedRoutes, validate)...)
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	if target == nil {
+		return nil
+	}
+	var registered []*RouteMetadata
+	for _, p := range prepared {
+		route := p.route
+		routeName := route.RouteName()
+		if takeover := takeovers[route]; takeover != nil {
+			takeover.takeOver(route, p.handler)
+		} else if err := handleRoute(target, route.Operation, routePattern(route.Operation.Path), routeName, p.handler); err != nil {
+			errs = append(errs, &RouteError{Route: route, Err: err})
+			continue
+		}
+		registered = append(registered, route)
+		wr := wrapped[route]
+		if !wr.authenticated && !route.Operation.DisableAuth {
+			logger.Warn("unauthenticated route", "error", &RouteError{Route: route, Err: fmt.Errorf("no authenticator registered, %s is served without authentication", routeName)})
+		}
+		reg.mu.Lock()
+		reg.routes = append(reg.routes, newRouteInfo(route, wr.authenticated))
+		reg.mu.Unlock()
+		if !route.Operation.DisableMetrics {
+			// Expose the route metrics before its first request.
+			reg.metrics.route(route)
+		}
+		logger.Info("registered route", "route", routeName, "method", route.Operation.Method, "path", route.Operation.Path, "handler", route.HandlerMethod, "timeout", time.Duration(route.Operation.Timeout)*time.Second)
+	}
+	errs = append(errs, reg.registerPreflights(target, key, existing, registered)...)
+	return errors.Join(errs...)
+}
+
+// pathKey identifies a path on a target, for state shared by the routes of that path.
+type pathKey struct {
+	target any
+	path   string
+}
+
+// targetKey returns the identity of target in path keys. Targets that cannot be compared get
+// a new identity, so their routes do not share state with later registrations.
+func targetKey(target RouteTarget) any {
+	if target == nil || reflect.TypeOf(target).Comparable() {
+		return target
+	}
+	return new(int)
+}
+
+// wrappedRoute is the handler of a route wrapped by wrapRoute.
+type wrappedRoute struct {
+	handler http.HandlerFunc
+	// authenticated reports whether the registered authenticator guards handler.
+	authenticated bool
+}
+
+// wrapRoute wraps handler with the middlewares, rate limit, caching, authentication and timeout
+// declared by the operation of route, with the conversion of its typed path variables, CORS headers,
+// metrics, access logging and tracing.
+func (reg *Registry) wrapRoute(route *RouteMetadata, handler http.HandlerFunc) (*wrappedRoute, error) {
+	_, params, err := compilePath(route.Operation.Path)
+	if err != nil {
+		return nil, err
+	}
+	handler, err = reg.ApplyMiddlewares(handler, *route.Operation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply middlewares to handler: %w", err)
+	}
+	// Limits keyed by principal need the authenticated request, the others also throttle
+	// requests failing authentication, e.g. credential guessing.
+	byPrincipal := principalRateLimit(route.Operation)
+	if byPrincipal {
+		if handler, err = reg.WithRateLimit(handler, route); err != nil {
+			return nil, err
+		}
+	}
+	handler = reg.withIfMatch(reg.withCaching(handler, route.Operation), route.Operation)
+	// Path variables are converted after authentication, so unauthenticated clients get 401, not 400.
+	handler, authenticated, err := reg.applyAuthentication(convertPathParams(handler, params), *route.Operation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply authentication to %s: %w", route.RouteName(), err)
+	}
+	if !byPrincipal {
+		if handler, err = reg.WithRateLimit(handler, route); err != nil {
+			return nil, err
 		}
-		routeName := fmt.Sprintf("%s.%s.%s", route.Package, route.HandlerType, route.HandlerMethod)
-		router.HandleFunc(route.Operation.Path, httpHandler).Methods(route.Operation.Method).Name(routeName)
-		log.Printf("Registered route %s: %s %s -> %s", routeName, route.Operation.Method, route.Operation.Path, route.HandlerMethod)
 	}
-	return nil
+	handler = reg.WithTimeout(handler, time.Duration(route.Operation.Timeout)*time.Second, route.RouteName())
+	handler = reg.WithMetrics(WithCORS(handler, route), route)
+	handler = reg.withTracing(reg.WithAccessLog(handler, route), route, authenticated)
+	return &wrappedRoute{handler: handler, authenticated: authenticated}, nil
+}
+
+// RegisterRoutes registers the routes annotated in handlerFile using the default registry.
+func RegisterRoutes(router *mux.Router, handler any, handlerFile string) error {
+	return defaultRegistry.RegisterRoutes(router, handler, handlerFile)
+}
+
+// RegisterRoutesFS registers the routes annotated in the files of fsys using the default registry.
+func RegisterRoutesFS(router *mux.Router, handler any, fsys fs.FS, pattern string) error {
+	return defaultRegistry.RegisterRoutesFS(router, handler, fsys, pattern)
+}
+
+// RegisterRoutesFSTo registers the routes annotated in the files of fsys on target using the default registry.
+func RegisterRoutesFSTo(target RouteTarget, handler any, fsys fs.FS, pattern string) error {
+	return defaultRegistry.RegisterRoutesFSTo(target, handler, fsys, pattern)
+}
+
+// RegisterPackageRoutes registers the routes annotated in patterns using the default registry.
+func RegisterPackageRoutes(router *mux.Router, handler any, patterns ...string) error {
+	return defaultRegistry.RegisterPackageRoutes(router, handler, patterns...)
+}
+
+// RegisterFuncRoutes registers the function routes annotated in handlerFile using the default registry.
+func RegisterFuncRoutes(router *mux.Router, funcs HandlerFuncs, handlerFile string) error {
+	return defaultRegistry.RegisterFuncRoutes(router, funcs, handlerFile)
+}
+
+// RegisterPackageFuncRoutes registers the function routes annotated in patterns using the default registry.
+func RegisterPackageFuncRoutes(router *mux.Router, funcs HandlerFuncs, patterns ...string) error {
+	return defaultRegistry.RegisterPackageFuncRoutes(router, funcs, patterns...)
+}
+
+// RegisterRoute registers a single annotated route using the default registry.
+func RegisterRoute(router *mux.Router, route *RouteMetadata, handler http.HandlerFunc) error {
+	return defaultRegistry.RegisterRoute(router, route, handler)
+}
+
+// RegisterBoundRoutesTo registers routes together on target using the default registry.
+func RegisterBoundRoutesTo(target RouteTarget, routes ...BoundRoute) error {
+	return defaultRegistry.RegisterBoundRoutesTo(target, routes...)
+}
+
+// RegisterRoutesTo registers the routes annotated in handlerFile on target using the default registry.
+func RegisterRoutesTo(target RouteTarget, handler any, handlerFile string) error {
+	return defaultRegistry.RegisterRoutesTo(target, handler, handlerFile)
+}
+
+// RegisterPackageRoutesTo registers the routes annotated in patterns on target using the default registry.
+func RegisterPackageRoutesTo(target RouteTarget, handler any, patterns ...string) error {
+	return defaultRegistry.RegisterPackageRoutesTo(target, handler, patterns...)
+}
+
+// RegisterFuncRoutesTo registers the function routes annotated in handlerFile on target using the default registry.
+func RegisterFuncRoutesTo(target RouteTarget, funcs HandlerFuncs, handlerFile string) error {
+	return defaultRegistry.RegisterFuncRoutesTo(target, funcs, handlerFile)
+}
+
+// RegisterPackageFuncRoutesTo registers the function routes annotated in patterns on target using the default registry.
+func RegisterPackageFuncRoutesTo(target RouteTarget, funcs HandlerFuncs, patterns ...string) error {
+	return defaultRegistry.RegisterPackageFuncRoutesTo(target, funcs, patterns...)
+}
+
+// RegisterRouteTo registers a single annotated route on target using the default registry.
+func RegisterRouteTo(target RouteTarget, route *RouteMetadata, handler http.HandlerFunc) error {
+	return defaultRegistry.RegisterRouteTo(target, route, handler)
 }
 
 func createHTTPHandler(method reflect.Value) (http.HandlerFunc, error) {
+	if isTypedHandlerFunc(method) {
+		return createTypedHandler(method), nil
+	}
 	if !isHTTPHandlerFunc(method) {
-		return nil, errors.New("method is not a http.HandlerFunc")
+		return nil, errors.New("method is not a http.HandlerFunc or func(context.Context, *T) (*R, error)")
 	}
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Prepare arguments as a slice of reflect.Value
@@ -64,5 +378,5 @@ func createHTTPHandler(method reflect.Value) (http.HandlerFunc, error) {
 // isHTTPHandlerFunc checks if a reflect.Value is an http.HandlerFunc
 func isHTTPHandlerFunc(funcValue reflect.Value) bool {
 	f := func(w http.ResponseWriter, r *http.Request) {}
-	return funcValue.Type() == reflect.TypeOf(f)
+	return funcValue.Type().ConvertibleTo(reflect.TypeOf(f))
 }
@@ -1,6 +1,7 @@
 package http
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -77,7 +78,7 @@ func TestRegisterRoutes(t *testing.T) {
 		handler := &Handler{}
 		err := RegisterRoutes(router, handler, "../example/person/handler.go")
 		require.Error(t, err)
-		assert.Contains(t, err.Error(), "method GetPersonHTTP not found in handler Handler")
+		assert.Contains(t, err.Error(), "method GetPerson not found in handler Handler")
 	})
 
 	t.Run("returns error when method is not a http.HandlerFunc", func(t *testing.T) {
@@ -97,3 +98,85 @@ func TestRegisterRoutes(t *testing.T) {
 		assert.Contains(t, err.Error(), "failed to apply middlewares")
 	})
 }
+
+func TestRegisterFuncRoutes(t *testing.T) {
+	file := createTempFile(t, `package api
+
+// @RestOperation( method = "GET", path = "/health", disableAuth = true )
+func Health(w http.ResponseWriter, r *http.Request) {}
+
+// @RestOperation( method = "GET", path = "/items/{id}", disableAuth = true )
+func GetItem(ctx context.Context, req *itemRequest) (*itemResponse, error) {}
+
+// @RestOperation( method = "GET", path = "/person/{uid}", disableAuth = true )
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {}
+`)
+	health := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}
+	getItem := func(ctx context.Context, req *itemRequest) (*itemResponse, error) {
+		return &itemResponse{ID: req.ID}, nil
+	}
+
+	t.Run("registers top-level functions by name", func(t *testing.T) {
+		registry := NewRegistry()
+		router := mux.NewRouter()
+		err := registry.RegisterFuncRoutes(router, HandlerFuncs{"Health": health, "GetItem": getItem}, file)
+		require.NoError(t, err)
+		require.NotNil(t, router.Get("api.Health"))
+		assert.Nil(t, router.Get("api.Handler.Get"))
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, httptest.NewRequest("GET", "/health", nil))
+		assert.Equal(t, http.StatusNoContent, res.Code)
+		res = httptest.NewRecorder()
+		router.ServeHTTP(res, httptest.NewRequest("GET", "/items/7", nil))
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Contains(t, res.Body.String(), `"id":7`)
+	})
+
+	t.Run("accepts http.HandlerFunc values", func(t *testing.T) {
+		router := mux.NewRouter()
+		err := NewRegistry().RegisterFuncRoutes(router, HandlerFuncs{"Health": http.HandlerFunc(health), "GetItem": getItem}, file)
+		assert.NoError(t, err)
+	})
+
+	t.Run("returns error when function is missing", func(t *testing.T) {
+		err := NewRegistry().RegisterFuncRoutes(mux.NewRouter(), HandlerFuncs{"Health": health}, file)
+		assert.ErrorContains(t, err, "function GetItem not found in handler funcs")
+	})
+
+	t.Run("struct registration skips top-level functions", func(t *testing.T) {
+		registry := NewRegistry()
+		require.NoError(t, registry.RegisterMiddleware("TestMiddleWare", testMiddleWare))
+		router := mux.NewRouter()
+		require.NoError(t, registry.RegisterRoutes(router, &Handler{}, file))
+		assert.NotNil(t, router.Get("api.Handler.Get"))
+		assert.Nil(t, router.Get("api.Health"))
+	})
+}
+
+func TestRegisterBoundRoutesTo(t *testing.T) {
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	route := func(method, path string, middlewares ...string) *RouteMetadata {
+		return &RouteMetadata{Operation: &RestOperation{Method: method, Path: path, Middlewares: middlewares, DisableAuth: true}, HandlerMethod: method + path, Package: "api"}
+	}
+
+	t.Run("registers nothing when a route fails", func(t *testing.T) {
+		registry := NewRegistry()
+		router := mux.NewRouter()
+		err := registry.RegisterBoundRoutesTo(MuxTarget(router),
+			BoundRoute{route("GET", "/a"), ok},
+			BoundRoute{route("GET", "/b", "missing"), ok},
+		)
+		assert.ErrorContains(t, err, "middleware missing not found")
+		assert.Nil(t, router.Get("api.GET/a"))
+		assert.Empty(t, registry.Routes())
+	})
+
+	t.Run("registers every route", func(t *testing.T) {
+		registry := NewRegistry()
+		router := mux.NewRouter()
+		require.NoError(t, registry.RegisterBoundRoutesTo(MuxTarget(router), BoundRoute{route("GET", "/a"), ok}, BoundRoute{route("GET", "/b"), ok}))
+		assert.Len(t, registry.Routes(), 2)
+	})
+}
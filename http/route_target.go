@@ -0,0 +1,237 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// RouteTarget is a router that annotated routes can be registered on. Paths use the
+// annotation syntax, "/person/{uid}" or "/person/{id:[0-9]+}", and are translated by each
+// implementation to its own. Handlers read path variables with PathParam whatever the target.
+//
+// Routers cannot remove routes, so registration is not atomic: every route of a batch is
+// checked before the first is handled, against a scratch router for the targets of this
+// package, but when Handle still fails, e.g. on a conflict with a route added by other code,
+// the routes handled before it stay registered.
+type RouteTarget interface {
+	// Handle registers handler for requests with method whose path matches path. name is
+	// the route name, used by routers that support named routes.
+	Handle(method, path, name string, handler http.HandlerFunc) error
+}
+
+// orderedTarget is implemented by targets that match routes in registration order, so
+// earlier routes can shadow later ones.
+type orderedTarget interface {
+	registeredRoutes() []registeredRoute
+}
+
+// validatingTarget is implemented by targets that can check the routes of a batch before
+// any is registered. validator returns a function registering each route on a scratch router.
+type validatingTarget interface {
+	validator() func(method, path string, match RouteMatch) error
+}
+
+func noopHandler(http.ResponseWriter, *http.Request) {}
+
+// PathParam returns the value of the path variable name of the route that matched r.
+func PathParam(r *http.Request, name string) string {
+	value, _ := pathParam(r, name)
+	return value
+}
+
+func pathParam(r *http.Request, name string) (string, bool) {
+	if value := r.PathValue(name); value != "" {
+		return value, true
+	}
+	// Handlers mounted on gorilla mux without a RouteTarget only have mux.Vars.
+	value, ok := mux.Vars(r)[name]
+	return value, ok
+}
+
+// MuxTarget returns a RouteTarget registering routes on a gorilla mux router.
+func MuxTarget(router *mux.Router) RouteTarget {
+	return muxTarget{router}
+}
+
+type muxTarget struct {
+	router *mux.Router
+}
+
+func (t muxTarget) Handle(method, path, name string, handler http.HandlerFunc) error {
+	return t.HandleMatching(method, path, name, RouteMatch{}, handler)
+}
+
+func (t muxTarget) HandleMatching(method, path, name string, match RouteMatch, handler http.HandlerFunc) error {
+	route := t.router.NewRoute()
+	if match.Host != "" {
+		route = route.Host(match.Host)
+	}
+	route = route.Path(path).Methods(method)
+	if len(match.Schemes) > 0 {
+		route = route.Schemes(match.Schemes...)
+	}
+	if len(match.Headers) > 0 {
+		route = route.Headers(matchPairs(match.Headers)...)
+	}
+	if len(match.Queries) > 0 {
+		route = route.Queries(matchPairs(match.Queries)...)
+	}
+	route.Handler(matchingHandler{match: match.key(), HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+		// Host and query variables are exposed like path variables.
+		for key, value := range mux.Vars(r) {
+			r.SetPathValue(key, value)
+		}
+		handler(w, r)
+	}}).Name(name)
+	return route.GetError()
+}
+
+// matchingHandler is the handler of routes added through MuxTarget. It records their
+// matchers, which gorilla mux does not expose, for the conflict checks of later registrations.
+type matchingHandler struct {
+	match string
+	http.HandlerFunc
+}
+
+func (t muxTarget) registeredRoutes() []registeredRoute {
+	return routerRoutes(t.router)
+}
+
+func (t muxTarget) validator() func(method, path string, match RouteMatch) error {
+	scratch := muxTarget{mux.NewRouter()}
+	return func(method, path string, match RouteMatch) error {
+		return scratch.HandleMatching(method, path, "", match, noopHandler)
+	}
+}
+
+// ServeMuxTarget returns a RouteTarget registering routes on a net/http ServeMux using
+// Go 1.22 method patterns. Variables with a regular expression become plain wildcards
+// whose value is checked before the handler runs, requests that do not match get 404.
+// A trailing {name:.*} matches the rest of the path. Variables must span a whole segment.
+func ServeMuxTarget(serveMux *http.ServeMux) RouteTarget {
+	return serveMuxTarget{serveMux}
+}
+
+type serveMuxTarget struct {
+	serveMux *http.ServeMux
+}
+
+func (t serveMuxTarget) Handle(method, path, name string, handler http.HandlerFunc) (err error) {
+	pattern, constraints, err := serveMuxPattern(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// ServeMux panics on invalid or conflicting patterns.
+		if p := recover(); p != nil {
+			err = fmt.Errorf("failed to register %s %s: %v", method, path, p)
+		}
+	}()
+	t.serveMux.HandleFunc(method+" "+pattern, constrainPathParams(handler, constraints))
+	return nil
+}
+
+func (t serveMuxTarget) validator() func(method, path string, match RouteMatch) error {
+	scratch := serveMuxTarget{http.NewServeMux()}
+	return func(method, path string, _ RouteMatch) error {
+		return scratch.Handle(method, path, "", noopHandler)
+	}
+}
+
+// serveMuxPattern translates an annotation path to a ServeMux pattern and the regular
+// expressions its variables must match.
+func serveMuxPattern(path string) (string, map[string]*regexp.Regexp, error) {
+	constraints := make(map[string]*regexp.Regexp)
+	segments := splitSegments(path)
+	for i, segment := range segments {
+		if !strings.Contains(segment, "{") {
+			continue
+		}
+		name, pattern, ok := pathVar(segment)
+		if !ok || len(splitTemplate(segment)) != 1 {
+			return "", nil, fmt.Errorf("path %s: ServeMux variables must span a whole segment", path)
+		}
+		segments[i] = "{" + name + "}"
+		if i == len(segments)-1 && (pattern == ".*" || pattern == ".+") {
+			segments[i] = "{" + name + "...}"
+		}
+		if pattern != "" && pattern != ".*" {
+			re, err := regexp.Compile("^(?:" + pattern + ")$")
+			if err != nil {
+				return "", nil, fmt.Errorf("path %s: invalid pattern for %s: %w", path, name, err)
+			}
+			constraints[name] = re
+		}
+	}
+	pattern := strings.Join(segments, "/")
+	if strings.HasSuffix(pattern, "/") {
+		// Without {$} a trailing slash matches the whole subtree.
+		pattern += "{$}"
+	}
+	return pattern, constraints, nil
+}
+
+func constrainPathParams(handler http.HandlerFunc, constraints map[string]*regexp.Regexp) http.HandlerFunc {
+	if len(constraints) == 0 {
+		return handler
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		for name, re := range constraints {
+			if !re.MatchString(r.PathValue(name)) {
+				http.NotFound(w, r)
+				return
+			}
+		}
+		handler(w, r)
+	}
+}
+
+// ChiRouter is the part of a chi router used by ChiTarget, implemented by chi.Router and chi.Mux.
+type ChiRouter interface {
+	MethodFunc(method, pattern string, handler http.HandlerFunc)
+}
+
+// ChiTarget returns a RouteTarget registering routes on a chi router. chi shares the
+// annotation syntax for variables, a trailing {name:.*} or {name:.+} becomes a catch-all,
+// as on ServeMuxTarget. Requests with an empty {name:.+} get 404.
+func ChiTarget(router ChiRouter) RouteTarget {
+	return chiTarget{router}
+}
+
+type chiTarget struct {
+	router ChiRouter
+}
+
+func (t chiTarget) Handle(method, path, name string, handler http.HandlerFunc) (err error) {
+	pattern, catchAll := path, ""
+	segments := splitSegments(path)
+	last := segments[len(segments)-1]
+	varName, re, ok := pathVar(last)
+	if ok && (re == ".*" || re == ".+") {
+		pattern, catchAll = strings.TrimSuffix(path, last)+"*", varName
+	}
+	if catchAll != "" {
+		next := handler
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			value := r.PathValue("*")
+			if value == "" && re == ".+" {
+				http.NotFound(w, r)
+				return
+			}
+			r.SetPathValue(catchAll, value)
+			next(w, r)
+		}
+	}
+	defer func() {
+		// chi panics on invalid patterns.
+		if p := recover(); p != nil {
+			err = fmt.Errorf("failed to register %s %s: %v", method, path, p)
+		}
+	}()
+	t.router.MethodFunc(method, pattern, handler)
+	return nil
+}
@@ -0,0 +1,144 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type targetHandler struct{}
+
+// @RestOperation( method = "GET", path = "/items/{id:[0-9]+}", disableAuth = true )
+func (h *targetHandler) GetItem(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("item " + PathParam(r, "id")))
+}
+
+// @RestOperation( method = "GET", path = "/files/{path:.*}", disableAuth = true )
+func (h *targetHandler) GetFile(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("file " + PathParam(r, "path")))
+}
+
+// @RestOperation( method = "GET", path = "/users/", disableAuth = true )
+func (h *targetHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("users"))
+}
+
+// fakeChi records the patterns registered through ChiTarget.
+type fakeChi struct {
+	patterns []string
+	handlers map[string]http.HandlerFunc
+}
+
+func (c *fakeChi) MethodFunc(method, pattern string, handler http.HandlerFunc) {
+	if c.handlers == nil {
+		c.handlers = make(map[string]http.HandlerFunc)
+	}
+	c.patterns = append(c.patterns, method+" "+pattern)
+	c.handlers[method+" "+pattern] = handler
+}
+
+func serve(handler http.Handler, path string) *httptest.ResponseRecorder {
+	return serveRequest(handler, httptest.NewRequest("GET", path, nil))
+}
+
+func serveRequest(handler http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+	return res
+}
+
+func TestServeMuxPattern(t *testing.T) {
+	tests := []struct {
+		path    string
+		pattern string
+	}{
+		{"/person/{uid}", "/person/{uid}"},
+		{"/items/{id:[0-9]+}", "/items/{id}"},
+		{"/files/{path:.*}", "/files/{path...}"},
+		{"/users/", "/users/{$}"},
+		{"/", "/{$}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			pattern, _, err := serveMuxPattern(tt.path)
+			require.NoError(t, err)
+			assert.Equal(t, tt.pattern, pattern)
+		})
+	}
+
+	t.Run("rejects partial segment variables", func(t *testing.T) {
+		_, _, err := serveMuxPattern("/files/{name}.{ext}")
+		assert.ErrorContains(t, err, "must span a whole segment")
+	})
+}
+
+func TestRouteTargets(t *testing.T) {
+	t.Run("registers on a ServeMux", func(t *testing.T) {
+		serveMux := http.NewServeMux()
+		require.NoError(t, NewRegistry().RegisterRoutesTo(ServeMuxTarget(serveMux), &targetHandler{}, "./route_target_test.go"))
+		assert.Equal(t, "item 42", serve(serveMux, "/items/42").Body.String())
+		assert.Equal(t, http.StatusNotFound, serve(serveMux, "/items/abc").Code)
+		assert.Equal(t, "file a/b.txt", serve(serveMux, "/files/a/b.txt").Body.String())
+		assert.Equal(t, "users", serve(serveMux, "/users/").Body.String())
+		assert.Equal(t, http.StatusNotFound, serve(serveMux, "/users/bill").Code)
+	})
+
+	t.Run("reports ServeMux conflicts as errors", func(t *testing.T) {
+		serveMux := http.NewServeMux()
+		registry := NewRegistry()
+		require.NoError(t, registry.RegisterRoutesTo(ServeMuxTarget(serveMux), &targetHandler{}, "./route_target_test.go"))
+		err := registry.RegisterRoutesTo(ServeMuxTarget(serveMux), &targetHandler{}, "./route_target_test.go")
+		assert.ErrorContains(t, err, "failed to register GET /items/{id:[0-9]+}")
+	})
+
+	t.Run("registers on gorilla mux", func(t *testing.T) {
+		router := mux.NewRouter()
+		require.NoError(t, NewRegistry().RegisterRoutesTo(MuxTarget(router), &targetHandler{}, "./route_target_test.go"))
+		assert.Equal(t, "item 42", serve(router, "/items/42").Body.String())
+		assert.Equal(t, http.StatusNotFound, serve(router, "/items/abc").Code)
+		assert.Equal(t, "file a/b.txt", serve(router, "/files/a/b.txt").Body.String())
+		assert.NotNil(t, router.Get("http.targetHandler.GetItem"))
+	})
+
+	t.Run("registers on chi", func(t *testing.T) {
+		router := &fakeChi{}
+		require.NoError(t, NewRegistry().RegisterRoutesTo(ChiTarget(router), &targetHandler{}, "./route_target_test.go"))
+		assert.ElementsMatch(t, []string{"GET /items/{id:[0-9]+}", "GET /files/*", "GET /users/"}, router.patterns)
+		req := httptest.NewRequest("GET", "/files/a/b.txt", nil)
+		req.SetPathValue("*", "a/b.txt")
+		res := httptest.NewRecorder()
+		router.handlers["GET /files/*"](res, req)
+		assert.Equal(t, "file a/b.txt", res.Body.String())
+	})
+
+	t.Run("maps non-empty chi catch-alls like ServeMux", func(t *testing.T) {
+		router := &fakeChi{}
+		route := &RouteMetadata{Operation: &RestOperation{Method: "GET", Path: "/files/{path:.+}", DisableAuth: true}, HandlerMethod: "GetFile"}
+		require.NoError(t, NewRegistry().RegisterBoundRoutesTo(ChiTarget(router), BoundRoute{Route: route, Handler: (&targetHandler{}).GetFile}))
+		assert.Equal(t, []string{"GET /files/*"}, router.patterns)
+		req := httptest.NewRequest("GET", "/files/a.txt", nil)
+		req.SetPathValue("*", "a.txt")
+		assert.Equal(t, "file a.txt", serveRequest(router.handlers["GET /files/*"], req).Body.String())
+		assert.Equal(t, http.StatusNotFound, serveRequest(router.handlers["GET /files/*"], httptest.NewRequest("GET", "/files/", nil)).Code)
+	})
+
+	t.Run("validates every route before registering on a ServeMux", func(t *testing.T) {
+		serveMux := http.NewServeMux()
+		err := NewRegistry().RegisterBoundRoutesTo(ServeMuxTarget(serveMux),
+			BoundRoute{Route: &RouteMetadata{Operation: &RestOperation{Method: "GET", Path: "/users/", DisableAuth: true}, HandlerMethod: "ListUsers"}, Handler: (&targetHandler{}).ListUsers},
+			BoundRoute{Route: &RouteMetadata{Operation: &RestOperation{Method: "GET", Path: "/files/{name}.{ext}", DisableAuth: true}, HandlerMethod: "GetFile"}, Handler: (&targetHandler{}).GetFile},
+		)
+		assert.ErrorContains(t, err, "must span a whole segment")
+		assert.Equal(t, http.StatusNotFound, serve(serveMux, "/users/").Code)
+	})
+
+	t.Run("path params fall back to mux vars", func(t *testing.T) {
+		req := mux.SetURLVars(httptest.NewRequest("GET", "/", nil), map[string]string{"id": "7"})
+		assert.Equal(t, "7", PathParam(req, "id"))
+		assert.Equal(t, "", PathParam(req, "missing"))
+	})
+}
@@ -0,0 +1,192 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// TimeoutObserver is called every time a route exceeds its timeout.
+// The timeout is passed along so it can be used as a metric label.
+type TimeoutObserver func(routeName string, timeout time.Duration, r *http.Request)
+
+// SetTimeoutResponse configures the response written when a route exceeds its timeout.
+// Only 503 Service Unavailable and 504 Gateway Timeout are accepted.
+func (reg *Registry) SetTimeoutResponse(status int, body string) error {
+	if status != http.StatusServiceUnavailable && status != http.StatusGatewayTimeout {
+		return fmt.Errorf("timeout status must be %d or %d, got %d", http.StatusServiceUnavailable, http.StatusGatewayTimeout, status)
+	}
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.timeoutStatus = status
+	reg.timeoutBody = body
+	return nil
+}
+
+// SetTimeoutObserver registers a callback invoked when a route times out. Pass nil to remove it.
+func (reg *Registry) SetTimeoutObserver(observer TimeoutObserver) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.timeoutObserver = observer
+}
+
+// WithTimeout wraps handler so the request context carries the given deadline.
+// Routes only get a timeout when their annotation or controller sets one.
+// Once the deadline passes the configured timeout response is sent and any
+// further writes by the handler fail with http.ErrHandlerTimeout. The response
+// is buffered until the handler returns or flushes it, see timeoutWriter.Flush.
+func (reg *Registry) WithTimeout(handler http.HandlerFunc, timeout time.Duration, routeName string) http.HandlerFunc {
+	if timeout <= 0 {
+		return handler
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		r = r.WithContext(ctx)
+		tw := &timeoutWriter{w: w, header: make(http.Header)}
+		done := make(chan struct{})
+		panicked := make(chan any, 1)
+		go func() {
+			defer func() {
+				if p := recover(); p != nil {
+					panicked <- p
+				}
+			}()
+			handler(tw, r)
+			close(done)
+		}()
+		select {
+		case p := <-panicked:
+			panic(p)
+		case <-done:
+			tw.mu.Lock()
+			defer tw.mu.Unlock()
+			tw.writeBuffered()
+		case <-ctx.Done():
+			tw.mu.Lock()
+			defer tw.mu.Unlock()
+			tw.timedOut = true
+			if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				// The client went away, there is nobody left to answer.
+				return
+			}
+			reg.mu.RLock()
+			status, body, observer := reg.timeoutStatus, reg.timeoutBody, reg.timeoutObserver
+			reg.mu.RUnlock()
+			if !tw.flushed {
+				// A flushed response is already on its way, it can only be cut short.
+				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+				w.WriteHeader(status)
+				w.Write([]byte(body))
+			}
+			countTimeout(r.Context())
+			if observer != nil {
+				observer(routeName, timeout, r)
+			}
+		}
+	}
+}
+
+// SetTimeoutResponse configures the timeout response of the default registry.
+func SetTimeoutResponse(status int, body string) error {
+	return defaultRegistry.SetTimeoutResponse(status, body)
+}
+
+// SetTimeoutObserver sets the timeout observer of the default registry.
+func SetTimeoutObserver(observer TimeoutObserver) {
+	defaultRegistry.SetTimeoutObserver(observer)
+}
+
+// WithTimeout wraps handler with a timeout using the options of the default registry.
+func WithTimeout(handler http.HandlerFunc, timeout time.Duration, routeName string) http.HandlerFunc {
+	return defaultRegistry.WithTimeout(handler, timeout, routeName)
+}
+
+// timeoutWriter buffers the handler response until it either completes, times out or is flushed.
+type timeoutWriter struct {
+	mu       sync.Mutex
+	w        http.ResponseWriter
+	header   http.Header
+	body     bytes.Buffer
+	status   int
+	timedOut bool
+	flushed  bool
+}
+
+// Header returns the buffered header map until the response is flushed, then the header
+// map of the response. After a timeout the handler gets a map of its own, so its late
+// writes cannot race with the timeout response.
+func (tw *timeoutWriter) Header() http.Header {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	switch {
+	case tw.timedOut:
+		return make(http.Header)
+	case tw.flushed:
+		return tw.w.Header()
+	}
+	return tw.header
+}
+
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if tw.status == 0 {
+		tw.status = http.StatusOK
+	}
+	if tw.flushed {
+		return tw.w.Write(p)
+	}
+	return tw.body.Write(p)
+}
+
+func (tw *timeoutWriter) WriteHeader(status int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.status != 0 {
+		return
+	}
+	tw.status = status
+}
+
+// Flush sends the response written so far and lets later writes through unbuffered, so
+// streaming handlers keep working. Once flushed, a timeout can no longer replace the response,
+// it only makes further writes fail.
+func (tw *timeoutWriter) Flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return
+	}
+	tw.writeBuffered()
+	http.NewResponseController(tw.w).Flush()
+}
+
+// Unwrap returns the response writer, so http.ResponseController reaches its SetWriteDeadline,
+// Hijack and EnableFullDuplex. Writes made on it directly bypass the buffering.
+func (tw *timeoutWriter) Unwrap() http.ResponseWriter {
+	return tw.w
+}
+
+// writeBuffered sends the buffered status, headers and body, unless they were already flushed.
+// tw.mu must be held.
+func (tw *timeoutWriter) writeBuffered() {
+	if tw.flushed {
+		return
+	}
+	tw.flushed = true
+	copyHeader(tw.w.Header(), tw.header)
+	if tw.status == 0 {
+		tw.status = http.StatusOK
+	}
+	tw.w.WriteHeader(tw.status)
+	tw.w.Write(tw.body.Bytes())
+	tw.body.Reset()
+}
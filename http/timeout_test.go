@@ -0,0 +1,185 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithTimeout(t *testing.T) {
+	t.Run("passes response through when handler finishes in time", func(t *testing.T) {
+		handler := func(w http.ResponseWriter, r *http.Request) {
+			_, ok := r.Context().Deadline()
+			assert.True(t, ok)
+			w.Header().Set("X-Custom", "test")
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte("done"))
+		}
+		wrapped := WithTimeout(handler, time.Second, "test.route")
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+		wrapped(res, req)
+		assert.Equal(t, http.StatusCreated, res.Code)
+		assert.Equal(t, "test", res.Header().Get("X-Custom"))
+		assert.Equal(t, "done", res.Body.String())
+	})
+
+	t.Run("returns 503 and blocks late writes when deadline passes", func(t *testing.T) {
+		writeErr := make(chan error, 1)
+		handler := func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+			time.Sleep(10 * time.Millisecond)
+			_, err := w.Write([]byte("too late"))
+			writeErr <- err
+		}
+		wrapped := WithTimeout(handler, 20*time.Millisecond, "test.route")
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+		wrapped(res, req)
+		assert.Equal(t, http.StatusServiceUnavailable, res.Code)
+		assert.Equal(t, "request timed out", res.Body.String())
+		assert.ErrorIs(t, <-writeErr, http.ErrHandlerTimeout)
+	})
+
+	t.Run("uses configured timeout response and notifies observer", func(t *testing.T) {
+		require.NoError(t, SetTimeoutResponse(http.StatusGatewayTimeout, "too slow"))
+		var gotRoute string
+		var gotTimeout time.Duration
+		SetTimeoutObserver(func(routeName string, timeout time.Duration, r *http.Request) {
+			gotRoute = routeName
+			gotTimeout = timeout
+		})
+		defer func() {
+			SetTimeoutResponse(http.StatusServiceUnavailable, "request timed out")
+			SetTimeoutObserver(nil)
+		}()
+		handler := func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+		}
+		wrapped := WithTimeout(handler, 10*time.Millisecond, "test.route")
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+		wrapped(res, req)
+		assert.Equal(t, http.StatusGatewayTimeout, res.Code)
+		assert.Equal(t, "too slow", res.Body.String())
+		assert.Equal(t, "test.route", gotRoute)
+		assert.Equal(t, 10*time.Millisecond, gotTimeout)
+	})
+
+	t.Run("keeps header writes after the deadline away from the response", func(t *testing.T) {
+		// Run with -race: the handler goroutine outlives the request.
+		wrote := make(chan struct{})
+		handler := func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Early", "1")
+			<-r.Context().Done()
+			time.Sleep(10 * time.Millisecond)
+			w.Header().Set("X-Late", "1")
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			close(wrote)
+		}
+		wrapped := WithTimeout(handler, 20*time.Millisecond, "test.route")
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+		wrapped(res, req)
+		header := res.Header().Clone()
+		<-wrote
+		assert.Equal(t, http.StatusServiceUnavailable, res.Code)
+		assert.Equal(t, "text/plain; charset=utf-8", header.Get("Content-Type"))
+		assert.Empty(t, header.Get("X-Early"))
+		assert.Empty(t, res.Header().Get("X-Late"))
+	})
+
+	t.Run("streams flushed responses and cuts them short on timeout", func(t *testing.T) {
+		writeErr := make(chan error, 1)
+		handler := func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Write([]byte("first "))
+			w.(http.Flusher).Flush()
+			w.Write([]byte("second "))
+			<-r.Context().Done()
+			time.Sleep(10 * time.Millisecond)
+			_, err := w.Write([]byte("too late"))
+			writeErr <- err
+		}
+		wrapped := WithTimeout(handler, 20*time.Millisecond, "test.route")
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+		wrapped(res, req)
+		assert.True(t, res.Flushed)
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, "text/event-stream", res.Header().Get("Content-Type"))
+		assert.ErrorIs(t, <-writeErr, http.ErrHandlerTimeout)
+		assert.Equal(t, "first second ", res.Body.String())
+	})
+
+	t.Run("lets the response controller reach the response writer", func(t *testing.T) {
+		var deadlineErr error
+		handler := func(w http.ResponseWriter, r *http.Request) {
+			deadlineErr = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(time.Minute))
+		}
+		res := &deadlineRecorder{ResponseRecorder: httptest.NewRecorder()}
+		WithTimeout(handler, time.Second, "test.route")(res, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.NoError(t, deadlineErr)
+		assert.False(t, res.deadline.IsZero())
+	})
+
+	t.Run("returns handler unchanged for zero timeout", func(t *testing.T) {
+		handler := func(w http.ResponseWriter, r *http.Request) {
+			_, ok := r.Context().Deadline()
+			assert.False(t, ok)
+		}
+		wrapped := WithTimeout(handler, 0, "test.route")
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		wrapped(httptest.NewRecorder(), req)
+	})
+}
+
+func TestRouteTimeout(t *testing.T) {
+	register := func(annotation string) *mux.Router {
+		op, err := ParseRestOperation(annotation)
+		require.NoError(t, err)
+		router := mux.NewRouter()
+		handler := func(w http.ResponseWriter, r *http.Request) {
+			_, ok := r.Context().Deadline()
+			w.Header().Set("X-Deadline", strconv.FormatBool(ok))
+		}
+		require.NoError(t, NewRegistry().RegisterRoute(router, &RouteMetadata{Operation: op, HandlerMethod: "Get", Package: "api"}, handler))
+		return router
+	}
+
+	t.Run("applies the timeout of the annotation", func(t *testing.T) {
+		res := serve(register(`@RestOperation( method = "GET", path = "/slow", timeout = 5, disableAuth = true )`), "/slow")
+		assert.Equal(t, "true", res.Header().Get("X-Deadline"))
+	})
+
+	t.Run("leaves routes without timeout unwrapped", func(t *testing.T) {
+		res := serve(register(`@RestOperation( method = "GET", path = "/stream", disableAuth = true )`), "/stream")
+		assert.Equal(t, "false", res.Header().Get("X-Deadline"))
+	})
+}
+
+func TestSetTimeoutResponse(t *testing.T) {
+	t.Run("rejects status other than 503 or 504", func(t *testing.T) {
+		err := SetTimeoutResponse(http.StatusInternalServerError, "")
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "timeout status must be")
+	})
+}
+
+// deadlineRecorder is a response recorder supporting write deadlines.
+type deadlineRecorder struct {
+	*httptest.ResponseRecorder
+	deadline time.Time
+}
+
+func (r *deadlineRecorder) SetWriteDeadline(deadline time.Time) error {
+	r.deadline = deadline
+	return nil
+}
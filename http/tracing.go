@@ -0,0 +1,116 @@
+package http
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation scope of the spans started by this package.
+const tracerName = "github.com/wellscui/go-rest-annotation/http"
+
+// SetTracerProvider sets the provider of the spans started for routes registered afterwards.
+// Pass nil to use the global provider, see otel.SetTracerProvider.
+func (reg *Registry) SetTracerProvider(provider trace.TracerProvider) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.tracerProvider = provider
+}
+
+// SetPropagator sets how the incoming trace context is extracted from request headers.
+// Pass nil to use the W3C traceparent header, the default.
+func (reg *Registry) SetPropagator(propagator propagation.TextMapPropagator) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.propagator = propagator
+}
+
+// EnableMiddlewareSpans turns child spans around each named middleware on or off, for routes
+// registered afterwards.
+func (reg *Registry) EnableMiddlewareSpans(enabled bool) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.middlewareSpans = enabled
+}
+
+func (reg *Registry) tracer() trace.Tracer {
+	reg.mu.RLock()
+	provider := reg.tracerProvider
+	reg.mu.RUnlock()
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	return provider.Tracer(tracerName)
+}
+
+// WithTracing wraps handler so every request of route runs in a server span named after the
+// route name and its templated path, continuing the trace of the incoming request. The span
+// carries the timeout, authentication and middlewares of the operation, and the response status.
+// Authentication is reported when the operation requires it and an authenticator is registered.
+func (reg *Registry) WithTracing(handler http.HandlerFunc, route *RouteMetadata) http.HandlerFunc {
+	reg.mu.RLock()
+	authenticated := !route.Operation.DisableAuth && reg.authenticator != nil
+	reg.mu.RUnlock()
+	return reg.withTracing(handler, route, authenticated)
+}
+
+// withTracing is WithTracing, with authenticated telling whether the authenticator guards handler.
+func (reg *Registry) withTracing(handler http.HandlerFunc, route *RouteMetadata, authenticated bool) http.HandlerFunc {
+	tracer := reg.tracer()
+	reg.mu.RLock()
+	propagator := reg.propagator
+	reg.mu.RUnlock()
+	if propagator == nil {
+		propagator = propagation.TraceContext{}
+	}
+	op := route.Operation
+	name := route.RouteName() + " " + op.Path
+	attrs := []attribute.KeyValue{
+		attribute.String("http.request.method", op.Method),
+		attribute.String("http.route", op.Path),
+		attribute.String("rest.route", route.RouteName()),
+		attribute.Int("rest.timeout", op.Timeout),
+		attribute.Bool("rest.auth", authenticated),
+		attribute.StringSlice("rest.middlewares", op.Middlewares),
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attrs...))
+		defer span.End()
+		rec := &statusRecorder{ResponseWriter: w}
+		handler(rec, r.WithContext(ctx))
+		status := rec.statusCode()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= 500 {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
+
+// withMiddlewareSpan wraps the handler built by a middleware in a child span named after its reference.
+func withMiddlewareSpan(tracer trace.Tracer, ref string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := tracer.Start(r.Context(), "middleware "+ref, trace.WithAttributes(attribute.String("rest.middleware", ref)))
+		defer span.End()
+		handler(w, r.WithContext(ctx))
+	}
+}
+
+// SetTracerProvider sets the tracer provider of the default registry.
+func SetTracerProvider(provider trace.TracerProvider) {
+	defaultRegistry.SetTracerProvider(provider)
+}
+
+// SetPropagator sets the trace context propagator of the default registry.
+func SetPropagator(propagator propagation.TextMapPropagator) {
+	defaultRegistry.SetPropagator(propagator)
+}
+
+// EnableMiddlewareSpans turns middleware spans on or off for the default registry.
+func EnableMiddlewareSpans(enabled bool) {
+	defaultRegistry.EnableMiddlewareSpans(enabled)
+}
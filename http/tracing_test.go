@@ -0,0 +1,116 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type tracedHandler struct{}
+
+// @RestOperation( method = "GET", path = "/orders/{id}", middlewares = ["First", "Second"], timeout = 5, disableAuth = true )
+func (h *tracedHandler) GetOrder(w http.ResponseWriter, r *http.Request) {
+	if PathParam(r, "id") == "fail" {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func TestTracing(t *testing.T) {
+	setup := func(t *testing.T, middlewareSpans bool) (http.Handler, *tracetest.InMemoryExporter) {
+		exporter := tracetest.NewInMemoryExporter()
+		registry := NewRegistry()
+		registry.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+		registry.EnableMiddlewareSpans(middlewareSpans)
+		passThrough := func(next http.HandlerFunc) http.HandlerFunc { return next }
+		require.NoError(t, registry.RegisterMiddleware("First", passThrough))
+		require.NoError(t, registry.RegisterMiddleware("Second", passThrough))
+		router := mux.NewRouter()
+		require.NoError(t, registry.RegisterRoutes(router, &tracedHandler{}, "./tracing_test.go"))
+		return router, exporter
+	}
+	attributes := func(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+		attrs := make(map[attribute.Key]attribute.Value)
+		for _, kv := range span.Attributes {
+			attrs[kv.Key] = kv.Value
+		}
+		return attrs
+	}
+
+	t.Run("starts a server span named after the route", func(t *testing.T) {
+		router, exporter := setup(t, false)
+		serve(router, "/orders/7")
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 1)
+		span := spans[0]
+		assert.Equal(t, "http.tracedHandler.GetOrder /orders/{id}", span.Name)
+		assert.Equal(t, trace.SpanKindServer, span.SpanKind)
+		attrs := attributes(span)
+		assert.Equal(t, "/orders/{id}", attrs["http.route"].AsString())
+		assert.Equal(t, int64(5), attrs["rest.timeout"].AsInt64())
+		assert.False(t, attrs["rest.auth"].AsBool())
+		assert.Equal(t, []string{"First", "Second"}, attrs["rest.middlewares"].AsStringSlice())
+		assert.Equal(t, int64(http.StatusOK), attrs["http.response.status_code"].AsInt64())
+	})
+
+	t.Run("continues the incoming trace", func(t *testing.T) {
+		router, exporter := setup(t, false)
+		req := httptest.NewRequest("GET", "/orders/7", nil)
+		req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		serveRequest(router, req)
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 1)
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].SpanContext.TraceID().String())
+		assert.Equal(t, "00f067aa0ba902b7", spans[0].Parent.SpanID().String())
+		assert.True(t, spans[0].Parent.IsRemote())
+	})
+
+	t.Run("marks server errors", func(t *testing.T) {
+		router, exporter := setup(t, false)
+		serve(router, "/orders/fail")
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 1)
+		assert.Equal(t, codes.Error, spans[0].Status.Code)
+	})
+
+	t.Run("starts child spans for middlewares", func(t *testing.T) {
+		router, exporter := setup(t, true)
+		serve(router, "/orders/7")
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 3)
+		// Spans are exported as they end, innermost first.
+		assert.Equal(t, "middleware Second", spans[0].Name)
+		assert.Equal(t, "middleware First", spans[1].Name)
+		assert.Equal(t, spans[1].SpanContext.SpanID(), spans[0].Parent.SpanID())
+		assert.Equal(t, spans[2].SpanContext.SpanID(), spans[1].Parent.SpanID())
+	})
+
+	t.Run("reports authentication only when an authenticator guards the route", func(t *testing.T) {
+		op, err := ParseRestOperation(`@RestOperation( method = "GET", path = "/private" )`)
+		require.NoError(t, err)
+		authAttr := func(t *testing.T, registry *Registry) bool {
+			exporter := tracetest.NewInMemoryExporter()
+			registry.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+			router := mux.NewRouter()
+			require.NoError(t, registry.RegisterRoute(router, &RouteMetadata{Operation: op, HandlerMethod: "Private", Package: "http"}, func(w http.ResponseWriter, r *http.Request) {}))
+			serve(router, "/private")
+			spans := exporter.GetSpans()
+			require.Len(t, spans, 1)
+			return attributes(spans[0])["rest.auth"].AsBool()
+		}
+		unguarded := NewRegistry()
+		unguarded.RequireAuthenticator(false)
+		assert.False(t, authAttr(t, unguarded))
+		guarded := NewRegistry()
+		require.NoError(t, guarded.RegisterAuthenticator(tokenAuthenticator{}))
+		assert.True(t, authAttr(t, guarded))
+	})
+}
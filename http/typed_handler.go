@@ -0,0 +1,292 @@
+package http
+
+import (
+	"context"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// StatusCoder is implemented by errors and responses that choose their own HTTP status code.
+type StatusCoder interface {
+	StatusCode() int
+}
+
+// Error is an error returned by typed handlers that maps to an HTTP status code.
+// Its message is sent to the client, unlike the message of other errors.
+type Error struct {
+	Status  int
+	Message string
+	Err     error
+}
+
+// NewError returns an Error with the given status and client-facing message.
+func NewError(status int, message string) *Error {
+	return &Error{Status: status, Message: message}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Message, e.Err)
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) StatusCode() int {
+	return e.Status
+}
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// TypedHandler adapts fn to an http.HandlerFunc. The request is bound into a new T, which
+// must be a struct, from the JSON body and from fields tagged `path:"name"`, `query:"name"` or
+// `header:"Name"`. Tagged fields are only bound from their parameter, never from the body.
+// A non-nil result is encoded as JSON, a nil result yields 204 No Content, and errors are
+// mapped to status codes through StatusCoder (500 otherwise).
+func TypedHandler[T, R any](fn func(context.Context, *T) (*R, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := new(T)
+		if err := bindRequest(r, req); err != nil {
+			writeError(w, err)
+			return
+		}
+		res, err := fn(r.Context(), req)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if res == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		writeJSON(w, res)
+	}
+}
+
+// isTypedHandlerFunc checks if a reflect.Value is a func(context.Context, *T) (*R, error) with T a struct.
+func isTypedHandlerFunc(funcValue reflect.Value) bool {
+	t := funcValue.Type()
+	return t.NumIn() == 2 && t.NumOut() == 2 &&
+		t.In(0) == contextType &&
+		t.In(1).Kind() == reflect.Ptr && t.In(1).Elem().Kind() == reflect.Struct &&
+		t.Out(0).Kind() == reflect.Ptr && t.Out(1) == errorType
+}
+
+func createTypedHandler(method reflect.Value) http.HandlerFunc {
+	reqType := method.Type().In(1).Elem()
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := reflect.New(reqType)
+		if err := bindRequest(r, req.Interface()); err != nil {
+			writeError(w, err)
+			return
+		}
+		out := method.Call([]reflect.Value{reflect.ValueOf(r.Context()), req})
+		if !out[1].IsNil() {
+			writeError(w, out[1].Interface().(error))
+			return
+		}
+		if out[0].IsNil() {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		writeJSON(w, out[0].Interface())
+	}
+}
+
+// bindRequest fills dst, a pointer to struct, from the request body, path, query and headers.
+// Binding failures are reported as *Error with status 400 or 415.
+func bindRequest(r *http.Request, dst any) error {
+	if r.Body != nil && r.Body != http.NoBody && r.ContentLength != 0 {
+		if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "" && mediaType != "application/json" {
+			return &Error{Status: http.StatusUnsupportedMediaType, Message: fmt.Sprintf("unsupported content type %s", mediaType)}
+		}
+		if err := json.NewDecoder(r.Body).Decode(dst); err != nil && !errors.Is(err, io.EOF) {
+			return &Error{Status: http.StatusBadRequest, Message: "invalid request body", Err: err}
+		}
+	}
+	if err := bindFields(r, reflect.ValueOf(dst).Elem()); err != nil {
+		return err
+	}
+	if v, ok := dst.(interface{ Validate() error }); ok {
+		if err := v.Validate(); err != nil {
+			var coder StatusCoder
+			if errors.As(err, &coder) {
+				return err
+			}
+			return &Error{Status: http.StatusBadRequest, Message: err.Error(), Err: err}
+		}
+	}
+	return nil
+}
+
+// bindFields sets the tagged fields of v, a struct, from their parameters. Fields whose
+// parameter is absent are cleared, so the body cannot set them.
+func bindFields(r *http.Request, v reflect.Value) error {
+	t := v.Type()
+	if t.Kind() != reflect.Struct {
+		return fmt.Errorf("request type %s is not a struct", t)
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if err := bindFields(r, v.Field(i)); err != nil {
+				return err
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		var values []string
+		var source, name string
+		if name = field.Tag.Get("path"); name != "" {
+			source = "path"
+			if value, ok := pathParam(r, name); ok {
+				values = []string{value}
+			}
+		} else if name = field.Tag.Get("query"); name != "" {
+			source = "query"
+			values = r.URL.Query()[name]
+		} else if name = field.Tag.Get("header"); name != "" {
+			source = "header"
+			values = r.Header.Values(name)
+		} else {
+			continue
+		}
+		v.Field(i).SetZero()
+		if len(values) == 0 {
+			continue
+		}
+		if err := setField(v.Field(i), values); err != nil {
+			return &Error{Status: http.StatusBadRequest, Message: fmt.Sprintf("invalid %s parameter %s", source, name), Err: err}
+		}
+	}
+	return nil
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// setField converts values to the field type. Slices receive every value, other kinds the first.
+func setField(field reflect.Value, values []string) error {
+	if reflect.PointerTo(field.Type()).Implements(textUnmarshalerType) {
+		return field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(values[0]))
+	}
+	switch field.Kind() {
+	case reflect.Ptr:
+		elem := reflect.New(field.Type().Elem())
+		if err := setField(elem.Elem(), values); err != nil {
+			return err
+		}
+		field.Set(elem)
+		return nil
+	case reflect.Slice:
+		slice := reflect.MakeSlice(field.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := setField(slice.Index(i), []string{value}); err != nil {
+				return err
+			}
+		}
+		field.Set(slice)
+		return nil
+	}
+	return setScalar(field, values[0])
+}
+
+func setScalar(field reflect.Value, value string) error {
+	if field.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
+
+// writeJSON encodes res as JSON with the status chosen by res when it implements StatusCoder.
+func writeJSON(w http.ResponseWriter, res any) {
+	status := http.StatusOK
+	if coder, ok := res.(StatusCoder); ok {
+		status = validStatus(coder.StatusCode())
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(res)
+}
+
+// validStatus returns status, or 500 when it is not a valid HTTP status code, on which
+// WriteHeader would panic or send a malformed response.
+func validStatus(status int) int {
+	if status < 100 || status > 599 {
+		return http.StatusInternalServerError
+	}
+	return status
+}
+
+// writeError maps err to a status code and writes it as {"error": message}. Only messages
+// of *Error values are sent to the client.
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	message := http.StatusText(status)
+	var coder StatusCoder
+	if errors.As(err, &coder) {
+		status = validStatus(coder.StatusCode())
+		message = http.StatusText(status)
+	}
+	var restErr *Error
+	if errors.As(err, &restErr) && restErr.Message != "" {
+		message = restErr.Message
+	}
+	if errors.Is(err, context.DeadlineExceeded) && coder == nil {
+		status = http.StatusGatewayTimeout
+		message = http.StatusText(status)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
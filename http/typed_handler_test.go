@@ -0,0 +1,167 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type itemRequest struct {
+	ID      int      `path:"id"`
+	Verbose bool     `query:"verbose"`
+	Tags    []string `query:"tag"`
+	Tenant  string   `header:"X-Tenant"`
+	Name    string   `json:"name"`
+}
+
+func (r *itemRequest) Validate() error {
+	if r.ID < 0 {
+		return errors.New("id must not be negative")
+	}
+	return nil
+}
+
+type itemResponse struct {
+	ID      int      `json:"id"`
+	Verbose bool     `json:"verbose"`
+	Tags    []string `json:"tags"`
+	Tenant  string   `json:"tenant"`
+	Name    string   `json:"name"`
+}
+
+type statusResponse struct {
+	Status int `json:"status"`
+}
+
+func (r *statusResponse) StatusCode() int {
+	return r.Status
+}
+
+type typedHandler struct{}
+
+// @RestOperation( method = "PUT", path = "/items/{id}", disableAuth = true )
+func (h *typedHandler) PutItem(ctx context.Context, req *itemRequest) (*itemResponse, error) {
+	switch req.ID {
+	case 404:
+		return nil, NewError(http.StatusNotFound, "item not found")
+	case 500:
+		return nil, errors.New("database password is wrong")
+	case 204:
+		return nil, nil
+	}
+	return &itemResponse{ID: req.ID, Verbose: req.Verbose, Tags: req.Tags, Tenant: req.Tenant, Name: req.Name}, nil
+}
+
+func TestTypedHandler(t *testing.T) {
+	router := mux.NewRouter()
+	require.NoError(t, RegisterRoutes(router, &typedHandler{}, "./typed_handler_test.go"))
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		return res
+	}
+
+	t.Run("binds path, query, header and body", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPut, "/items/7?verbose=true&tag=a&tag=b", strings.NewReader(`{"name":"pen"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Tenant", "acme")
+		res := serve(req)
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, "application/json", res.Header().Get("Content-Type"))
+		assert.JSONEq(t, `{"id":7,"verbose":true,"tags":["a","b"],"tenant":"acme","name":"pen"}`, res.Body.String())
+	})
+
+	t.Run("returns 400 for invalid parameter", func(t *testing.T) {
+		res := serve(httptest.NewRequest(http.MethodPut, "/items/abc", nil))
+		assert.Equal(t, http.StatusBadRequest, res.Code)
+		assert.JSONEq(t, `{"error":"invalid path parameter id"}`, res.Body.String())
+	})
+
+	t.Run("returns 400 for invalid body", func(t *testing.T) {
+		res := serve(httptest.NewRequest(http.MethodPut, "/items/1", strings.NewReader(`{`)))
+		assert.Equal(t, http.StatusBadRequest, res.Code)
+	})
+
+	t.Run("returns 400 when validation fails", func(t *testing.T) {
+		res := serve(httptest.NewRequest(http.MethodPut, "/items/-1", nil))
+		assert.Equal(t, http.StatusBadRequest, res.Code)
+		assert.JSONEq(t, `{"error":"id must not be negative"}`, res.Body.String())
+	})
+
+	t.Run("returns 415 for unsupported content type", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPut, "/items/1", strings.NewReader(`name=pen`))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		res := serve(req)
+		assert.Equal(t, http.StatusUnsupportedMediaType, res.Code)
+	})
+
+	t.Run("maps Error to its status", func(t *testing.T) {
+		res := serve(httptest.NewRequest(http.MethodPut, "/items/404", nil))
+		assert.Equal(t, http.StatusNotFound, res.Code)
+		assert.JSONEq(t, `{"error":"item not found"}`, res.Body.String())
+	})
+
+	t.Run("hides message of unexpected errors", func(t *testing.T) {
+		res := serve(httptest.NewRequest(http.MethodPut, "/items/500", nil))
+		assert.Equal(t, http.StatusInternalServerError, res.Code)
+		assert.JSONEq(t, `{"error":"Internal Server Error"}`, res.Body.String())
+	})
+
+	t.Run("returns 204 for nil result", func(t *testing.T) {
+		res := serve(httptest.NewRequest(http.MethodPut, "/items/204", nil))
+		assert.Equal(t, http.StatusNoContent, res.Code)
+		assert.Empty(t, res.Body.String())
+	})
+
+	t.Run("adapts typed function without reflection", func(t *testing.T) {
+		h := &typedHandler{}
+		router := mux.NewRouter()
+		router.HandleFunc("/items/{id}", TypedHandler(h.PutItem))
+		req := httptest.NewRequest(http.MethodPut, "/items/3", nil)
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.JSONEq(t, `{"id":3,"verbose":false,"tags":null,"tenant":"","name":""}`, res.Body.String())
+	})
+
+	t.Run("does not bind tagged fields from the body", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPut, "/items/7", strings.NewReader(`{"name":"pen","ID":9,"Tenant":"evil","Verbose":true}`))
+		req.Header.Set("Content-Type", "application/json")
+		res := serve(req)
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.JSONEq(t, `{"id":7,"verbose":false,"tags":null,"tenant":"","name":"pen"}`, res.Body.String())
+	})
+
+	t.Run("returns 500 instead of panicking for non-struct requests", func(t *testing.T) {
+		handler := TypedHandler(func(ctx context.Context, req *string) (*string, error) { return req, nil })
+		res := httptest.NewRecorder()
+		handler(res, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`"pen"`)))
+		assert.Equal(t, http.StatusInternalServerError, res.Code)
+	})
+
+	t.Run("returns 500 for invalid status codes", func(t *testing.T) {
+		failing := TypedHandler(func(ctx context.Context, req *struct{}) (*struct{}, error) {
+			return nil, NewError(0, "no status")
+		})
+		res := httptest.NewRecorder()
+		failing(res, httptest.NewRequest(http.MethodPost, "/", nil))
+		assert.Equal(t, http.StatusInternalServerError, res.Code)
+		assert.JSONEq(t, `{"error":"no status"}`, res.Body.String())
+
+		for _, status := range []int{0, 99, 600, 1000} {
+			responding := TypedHandler(func(ctx context.Context, req *struct{}) (*statusResponse, error) {
+				return &statusResponse{Status: status}, nil
+			})
+			res := httptest.NewRecorder()
+			responding(res, httptest.NewRequest(http.MethodPost, "/", nil))
+			assert.Equal(t, http.StatusInternalServerError, res.Code, status)
+		}
+	})
+}
@@ -0,0 +1,138 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// RouteError reports a route that cannot be registered, together with the source position
+// of its annotation. Registration returns every RouteError joined with errors.Join, use
+// errors.As to inspect them.
+type RouteError struct {
+	Route *RouteMetadata
+	Err   error
+}
+
+func (e *RouteError) Error() string {
+	if pos := e.Route.Position(); pos != "" {
+		return fmt.Sprintf("%s: %s: %v", pos, e.Route.RouteName(), e.Err)
+	}
+	return fmt.Sprintf("%s: %v", e.Route.RouteName(), e.Err)
+}
+
+func (e *RouteError) Unwrap() error {
+	return e.Err
+}
+
+// ValidateRoutes checks the routes annotated in the files matched by patterns (see ParsePackages)
+// against handler without touching a router: every method must exist with a supported signature,
+// every middleware must be registered, authentication must be available and no route may be
+// declared twice. All problems are returned joined, which makes it suitable for unit tests and CI.
+func (reg *Registry) ValidateRoutes(handler any, patterns ...string) error {
+	routes, err := ParsePackages(patterns...)
+	if err != nil {
+		return fmt.Errorf("failed to parse routes: %w", err)
+	}
+	return reg.registerHandlerRoutes(nil, handler, routes)
+}
+
+// ValidateFuncRoutes is the ValidateRoutes counterpart for top-level function handlers.
+func (reg *Registry) ValidateFuncRoutes(funcs HandlerFuncs, patterns ...string) error {
+	routes, err := ParsePackages(patterns...)
+	if err != nil {
+		return fmt.Errorf("failed to parse routes: %w", err)
+	}
+	return reg.registerFuncRoutes(nil, funcs, routes)
+}
+
+// ValidateRoutes validates the routes of handler using the default registry.
+func ValidateRoutes(handler any, patterns ...string) error {
+	return defaultRegistry.ValidateRoutes(handler, patterns...)
+}
+
+// ValidateFuncRoutes validates the function routes in patterns using the default registry.
+func ValidateFuncRoutes(funcs HandlerFuncs, patterns ...string) error {
+	return defaultRegistry.ValidateFuncRoutes(funcs, patterns...)
+}
+
+// boundRoute is an annotated route together with the function implementing it.
+type boundRoute struct {
+	route   *RouteMetadata
+	handler http.HandlerFunc
+}
+
+// bindMethods resolves the methods of handler implementing routes. Routes of other types or
+// packages are skipped, problems with the remaining ones are collected as *RouteError. It fails
+// when routes of the handler type exist but all of them were declared in other packages.
+func bindMethods(handler any, routes []*RouteMetadata) ([]boundRoute, []error, error) {
+	handlerValue := reflect.ValueOf(handler)
+	handlerType := reflect.TypeOf(handler)
+	if handlerType == nil || handlerType.Kind() != reflect.Ptr || handlerType.Elem().Kind() != reflect.Struct {
+		return nil, nil, fmt.Errorf("handler must be a pointer to struct")
+	}
+	handlerType = handlerType.Elem()
+	var bound []boundRoute
+	var errs []error
+	var otherPackages []string
+	for _, route := range routes {
+		if route.HandlerType != handlerType.Name() {
+			continue
+		}
+		if !samePackage(route, handlerType.PkgPath()) {
+			otherPackages = append(otherPackages, route.ImportPath)
+			continue
+		}
+		method := handlerValue.MethodByName(route.HandlerMethod)
+		if !method.IsValid() {
+			errs = append(errs, &RouteError{Route: route, Err: fmt.Errorf("method %s not found in handler %s", route.HandlerMethod, route.HandlerType)})
+			continue
+		}
+		httpHandler, err := createHTTPHandler(method)
+		if err != nil {
+			errs = append(errs, &RouteError{Route: route, Err: fmt.Errorf("failed to create http handler for %s.%s : %w", route.HandlerType, route.HandlerMethod, err)})
+			continue
+		}
+		bound = append(bound, boundRoute{route, httpHandler})
+	}
+	if len(bound) == 0 && len(errs) == 0 && len(otherPackages) > 0 {
+		return nil, nil, fmt.Errorf("handler %s of package %s matches no routes, routes of %s are declared in %s",
+			handlerType.Name(), handlerType.PkgPath(), handlerType.Name(), strings.Join(otherPackages, ", "))
+	}
+	return bound, errs, nil
+}
+
+// samePackage reports whether route was declared in the package of a handler type with import
+// path pkgPath. Types of package main report "main" rather than their import path.
+func samePackage(route *RouteMetadata, pkgPath string) bool {
+	return route.ImportPath == "" || route.ImportPath == pkgPath || pkgPath == "main" && route.Package == "main"
+}
+
+// bindFuncs resolves the functions in funcs implementing the top-level function routes.
+func bindFuncs(funcs HandlerFuncs, routes []*RouteMetadata) ([]boundRoute, []error) {
+	var bound []boundRoute
+	var errs []error
+	for _, route := range routes {
+		if route.HandlerType != "" {
+			continue
+		}
+		fn, ok := funcs[route.HandlerMethod]
+		if !ok || fn == nil {
+			errs = append(errs, &RouteError{Route: route, Err: fmt.Errorf("function %s not found in handler funcs", route.HandlerMethod)})
+			continue
+		}
+		fnValue := reflect.ValueOf(fn)
+		if fnValue.Kind() != reflect.Func {
+			errs = append(errs, &RouteError{Route: route, Err: fmt.Errorf("handler for %s is a %T, not a function", route.HandlerMethod, fn)})
+			continue
+		}
+		httpHandler, err := createHTTPHandler(fnValue)
+		if err != nil {
+			errs = append(errs, &RouteError{Route: route, Err: fmt.Errorf("failed to create http handler for %s : %w", route.RouteName(), err)})
+			continue
+		}
+		bound = append(bound, boundRoute{route, httpHandler})
+	}
+	return bound, errs
+}
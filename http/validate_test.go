@@ -0,0 +1,106 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type brokenHandler struct{}
+
+func (h *brokenHandler) List(w http.ResponseWriter, r *http.Request) {}
+
+func (h *brokenHandler) Wrong(w http.ResponseWriter) {}
+
+func TestValidateRoutes(t *testing.T) {
+	file := createTempFile(t, `package http
+
+// @RestOperation( method = "GET", path = "/items", disableAuth = true )
+func (h *brokenHandler) List(w http.ResponseWriter, r *http.Request) {}
+
+// @RestOperation( method = "GET", path = "/missing", disableAuth = true )
+func (h *brokenHandler) Missing(w http.ResponseWriter, r *http.Request) {}
+
+// @RestOperation( method = "GET", path = "/wrong", disableAuth = true )
+func (h *brokenHandler) Wrong(w http.ResponseWriter) {}
+
+// @RestOperation( method = "GET", path = "/items", middlewares = ["unknown"], disableAuth = true )
+func (h *brokenHandler) List(w http.ResponseWriter, r *http.Request) {}
+
+// @RestOperation( method = "GET", path = "/items", disableAuth = true )
+func (h *brokenHandler) List(w http.ResponseWriter, r *http.Request) {}
+`)
+
+	t.Run("collects every problem with its position", func(t *testing.T) {
+		err := NewRegistry().ValidateRoutes(&brokenHandler{}, file)
+		require.Error(t, err)
+		msg := err.Error()
+		assert.Contains(t, msg, file+":6: http.brokenHandler.Missing: method Missing not found in handler brokenHandler")
+		assert.Contains(t, msg, file+":9: http.brokenHandler.Wrong: failed to create http handler")
+		assert.Contains(t, msg, file+":12: http.brokenHandler.List: failed to apply middlewares to handler: middleware unknown not found")
+		assert.Contains(t, msg, file+":15: http.brokenHandler.List: duplicate route GET /items, already registered by http.brokenHandler.List")
+		var routeErr *RouteError
+		require.ErrorAs(t, err, &routeErr)
+		assert.Equal(t, 6, routeErr.Route.Line)
+		assert.Len(t, err.(interface{ Unwrap() []error }).Unwrap(), 4)
+	})
+
+	t.Run("registers nothing when a route fails", func(t *testing.T) {
+		router := mux.NewRouter()
+		registry := NewRegistry()
+		err := registry.RegisterRoutes(router, &brokenHandler{}, file)
+		require.Error(t, err)
+		assert.Nil(t, router.Get("http.brokenHandler.List"))
+		assert.Empty(t, registry.Routes())
+	})
+
+	t.Run("passes for valid routes", func(t *testing.T) {
+		registry := NewRegistry()
+		require.NoError(t, registry.RegisterMiddleware("TestMiddleWare", testMiddleWare))
+		assert.NoError(t, registry.ValidateRoutes(&Handler{}, "./route_register_test.go"))
+		assert.Empty(t, registry.Routes())
+	})
+
+	t.Run("detects route already on the router", func(t *testing.T) {
+		registry := NewRegistry()
+		require.NoError(t, registry.RegisterMiddleware("TestMiddleWare", testMiddleWare))
+		router := mux.NewRouter()
+		require.NoError(t, registry.RegisterRoutes(router, &Handler{}, "./route_register_test.go"))
+		err := registry.RegisterRoutes(router, &Handler{}, "./route_register_test.go")
+		assert.ErrorContains(t, err, "duplicate route GET /person/{uid}, already registered by http.Handler.Get")
+	})
+
+	t.Run("validates function routes", func(t *testing.T) {
+		file := createTempFile(t, `package api
+
+// @RestOperation( method = "GET", path = "/health" )
+func Health(w http.ResponseWriter, r *http.Request) {}
+`)
+		err := NewRegistry().ValidateFuncRoutes(HandlerFuncs{}, file)
+		assert.ErrorContains(t, err, "function Health not found in handler funcs")
+		var routeErr *RouteError
+		assert.True(t, errors.As(err, &routeErr))
+	})
+
+	t.Run("fails when the routes of the handler are declared in another package", func(t *testing.T) {
+		routes := []*RouteMetadata{{
+			Operation:     &RestOperation{Method: "GET", Path: "/items"},
+			HandlerType:   "brokenHandler",
+			HandlerMethod: "List",
+			Package:       "http",
+			ImportPath:    "example.com/other/http",
+		}}
+		_, _, err := bindMethods(&brokenHandler{}, routes)
+		assert.ErrorContains(t, err, "matches no routes, routes of brokenHandler are declared in example.com/other/http")
+	})
+
+	t.Run("matches handlers of package main by package name", func(t *testing.T) {
+		route := &RouteMetadata{Package: "main", ImportPath: "example.com/svc/cmd/server"}
+		assert.True(t, samePackage(route, "main"))
+		assert.False(t, samePackage(&RouteMetadata{Package: "api", ImportPath: "example.com/svc/api"}, "main"))
+	})
+}